@@ -0,0 +1,362 @@
+// Package api exposes the haproxy runtime api as a JSON REST api
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// The roles a token can have
+const (
+	RoleRead  = "read"  // Can only use GET routes
+	RoleAdmin = "admin" // Can use all routes
+)
+
+// ServerT is the http handler of the api, create one using New
+type ServerT struct {
+	h      *haproxysocket.HaproxyInstace
+	routes []routeT
+
+	// Tokens maps an api token to its role, requests must send "Authorization: Bearer <token>"
+	Tokens map[string]string
+	// Names maps an api token to the name recorded as actor in the haproxy audit log, see haproxysocket.HaproxyInstace.As
+	// Tokens without a name are recorded as api:<role>:<first 8 characters of the sha256 of the token>
+	Names  map[string]string
+	Title  string   // The title used in the OpenAPI description
+	Stream *StreamT // Serves GET /stream, haproxy is only polled while there are clients
+}
+
+// New creates the api for a haproxy instance
+func New(h *haproxysocket.HaproxyInstace, tokens map[string]string) *ServerT {
+	return &ServerT{
+		h:      h,
+		routes: routes,
+		Tokens: tokens,
+		Title:  "haproxy runtime api",
+		Stream: NewStream(h),
+	}
+}
+
+// fieldT describes a path parameter, query parameter or body field
+type fieldT struct {
+	name     string
+	kind     string // string or integer
+	required bool
+	enum     []string
+	help     string
+}
+
+// routeT is a single api endpoint
+type routeT struct {
+	method  string
+	path    string // Path parameters look like {name}
+	role    string
+	summary string
+	query   []fieldT
+	body    []fieldT
+	handler func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error)
+	stream  bool // The route is served by the Stream instead of the handler
+}
+
+// requestT contains the validated input of a request
+type requestT struct {
+	params map[string]string
+	query  map[string]string
+	body   map[string]interface{}
+}
+
+// str returns a path parameter, query parameter or body field as string
+func (r *requestT) str(name string) string {
+	if value, ok := r.params[name]; ok {
+		return value
+	}
+	if value, ok := r.query[name]; ok {
+		return value
+	}
+	switch value := r.body[name].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// uint returns an integer body field
+func (r *requestT) uint(name string) uint {
+	value, _ := r.body[name].(float64)
+	return uint(value)
+}
+
+// apiError is an error with a http status code
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e apiError) Error() string {
+	return e.message
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway // Errors from haproxy
+	var e apiError
+	if errors.As(err, &e) {
+		status = e.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// ServeHTTP handles an api request
+// GET /openapi.json is served without a token so tools can discover the api
+func (s *ServerT) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == "/openapi.json" {
+		writeJSON(w, http.StatusOK, s.OpenAPI())
+		return
+	}
+
+	route, params, err := s.match(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	role, actor := s.role(r, route)
+	if role == "" {
+		writeError(w, apiError{http.StatusUnauthorized, "missing or invalid token"})
+		return
+	}
+	if route.role == RoleAdmin && role != RoleAdmin {
+		writeError(w, apiError{http.StatusForbidden, "this route requires the admin role"})
+		return
+	}
+
+	req, err := route.validate(r, params)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if route.stream {
+		if s.Stream == nil {
+			writeError(w, apiError{http.StatusNotFound, "not found"})
+			return
+		}
+		s.Stream.ServeHTTP(w, r)
+		return
+	}
+	result, err := route.handler(s.h.As(actor), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if result == nil {
+		result = map[string]bool{"ok": true}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// role returns the role and actor name of the token in the request, empty if the token is missing or invalid
+// Streaming routes also accept the token as query parameter as browsers can't set headers on an EventSource or WebSocket
+func (s *ServerT) role(r *http.Request, route routeT) (string, string) {
+	header := r.Header.Get("Authorization")
+	var token []byte
+	if strings.HasPrefix(header, "Bearer ") {
+		token = []byte(strings.TrimPrefix(header, "Bearer "))
+	} else if route.stream && r.URL.Query().Get("token") != "" {
+		token = []byte(r.URL.Query().Get("token"))
+	} else {
+		return "", ""
+	}
+	role := ""
+	actor := ""
+	for known, knownRole := range s.Tokens {
+		// Compare all tokens in constant time so the tokens can't be guessed using the response time
+		if known != "" && subtle.ConstantTimeCompare([]byte(known), token) == 1 {
+			role = knownRole
+			actor = s.Names[known]
+		}
+	}
+	if role != "" && actor == "" {
+		hash := sha256.Sum256(token)
+		actor = "api:" + role + ":" + hex.EncodeToString(hash[:])[:8]
+	}
+	return role, actor
+}
+
+// match finds the route of a request and returns its path parameters
+// Path parameters can contain slashes if they are escaped as %2F, for example map file names
+func (s *ServerT) match(r *http.Request) (routeT, map[string]string, error) {
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	pathFound := false
+	for _, route := range s.routes {
+		routeSegments := strings.Split(strings.Trim(route.path, "/"), "/")
+		if len(routeSegments) != len(segments) {
+			continue
+		}
+		params := map[string]string{}
+		matches := true
+		for i, routeSegment := range routeSegments {
+			if strings.HasPrefix(routeSegment, "{") {
+				value, err := url.PathUnescape(segments[i])
+				if err != nil || value == "" {
+					matches = false
+					break
+				}
+				params[strings.Trim(routeSegment, "{}")] = value
+				continue
+			}
+			if routeSegment != segments[i] {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		pathFound = true
+		if route.method == r.Method {
+			return route, params, nil
+		}
+	}
+	if pathFound {
+		return routeT{}, nil, apiError{http.StatusMethodNotAllowed, "method " + r.Method + " not allowed"}
+	}
+	return routeT{}, nil, apiError{http.StatusNotFound, "not found"}
+}
+
+// validate checks the path parameters, query parameters and body of a request
+func (route routeT) validate(r *http.Request, params map[string]string) (*requestT, error) {
+	req := &requestT{
+		params: params,
+		query:  map[string]string{},
+		body:   map[string]interface{}{},
+	}
+	for name, value := range params {
+		err := checkString(fieldT{name: name}, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := r.URL.Query()
+	for name := range query {
+		if fieldByName(route.query, name) == nil {
+			return nil, apiError{http.StatusBadRequest, "unknown query parameter " + name}
+		}
+	}
+	for _, field := range route.query {
+		value := query.Get(field.name)
+		if value == "" {
+			if field.required {
+				return nil, apiError{http.StatusBadRequest, "missing query parameter " + field.name}
+			}
+			continue
+		}
+		err := checkString(field, value)
+		if err != nil {
+			return nil, err
+		}
+		req.query[field.name] = value
+	}
+
+	if len(route.body) == 0 {
+		return req, nil
+	}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.UseNumber()
+	raw := map[string]interface{}{}
+	err := decoder.Decode(&raw)
+	if err != nil {
+		return nil, apiError{http.StatusBadRequest, "invalid json body: " + err.Error()}
+	}
+	for name := range raw {
+		if fieldByName(route.body, name) == nil {
+			return nil, apiError{http.StatusBadRequest, "unknown field " + name}
+		}
+	}
+	for _, field := range route.body {
+		value, ok := raw[field.name]
+		if !ok || value == nil {
+			if field.required {
+				return nil, apiError{http.StatusBadRequest, "missing field " + field.name}
+			}
+			continue
+		}
+		switch field.kind {
+		case "integer":
+			number, ok := value.(json.Number)
+			if !ok {
+				return nil, apiError{http.StatusBadRequest, field.name + " must be an integer"}
+			}
+			i, err := strconv.ParseUint(number.String(), 10, 32)
+			if err != nil {
+				return nil, apiError{http.StatusBadRequest, field.name + " must be a positive integer"}
+			}
+			req.body[field.name] = float64(i)
+		default:
+			str, ok := value.(string)
+			if !ok {
+				return nil, apiError{http.StatusBadRequest, field.name + " must be a string"}
+			}
+			if field.required && str == "" {
+				return nil, apiError{http.StatusBadRequest, field.name + " can't be empty"}
+			}
+			err := checkString(field, str)
+			if err != nil {
+				return nil, err
+			}
+			req.body[field.name] = str
+		}
+	}
+	return req, nil
+}
+
+func fieldByName(fields []fieldT, name string) *fieldT {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// checkString validates a string value, it must not contain characters that change the meaning of a socket command
+// Spaces are never allowed as haproxy splits the arguments of a command on spaces
+func checkString(field fieldT, value string) error {
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return apiError{http.StatusBadRequest, field.name + " contains control characters"}
+		}
+		if r == ' ' {
+			return apiError{http.StatusBadRequest, field.name + " can't contain spaces"}
+		}
+	}
+	if strings.Contains(value, "<<") || strings.Contains(value, ";") {
+		return apiError{http.StatusBadRequest, field.name + " contains invalid characters"}
+	}
+	if len(field.enum) > 0 {
+		for _, allowed := range field.enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return apiError{http.StatusBadRequest, field.name + " must be one of " + strings.Join(field.enum, ", ")}
+	}
+	return nil
+}
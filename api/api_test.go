@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// testInstance returns an instance that answers queries with canned output instead of connecting to haproxy
+func testInstance(t *testing.T, responses map[string]string) *haproxysocket.HaproxyInstace {
+	h := haproxysocket.New("unix", "/nonexistent.sock")
+	h.Interceptors = []haproxysocket.InterceptorT{func(c *haproxysocket.CommandT, next func() (string, error)) (string, error) {
+		out, ok := responses[c.Query]
+		if !ok {
+			t.Errorf("unexpected query %q", c.Query)
+		}
+		return out, nil
+	}}
+	return h
+}
+
+func TestMapValues(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		responses      map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "add",
+			method:         http.MethodPost,
+			path:           "/maps/%230",
+			body:           `{"key": "example.com", "value": "be_app"}`,
+			responses:      map[string]string{"add map #0 example.com be_app": ""},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "add with spaces",
+			method:         http.MethodPost,
+			path:           "/maps/%230",
+			body:           `{"key": "example.com", "value": "be_app be_other"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "set",
+			method:         http.MethodPut,
+			path:           "/maps/%230/example.com",
+			body:           `{"value": "be_static"}`,
+			responses:      map[string]string{"set map #0 example.com be_static": ""},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "set with spaces",
+			method:         http.MethodPut,
+			path:           "/maps/%230/example.com",
+			body:           `{"value": "be_static extra"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := New(testInstance(t, test.responses), map[string]string{"admin-token": RoleAdmin})
+			r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			r.Header.Set("Authorization", "Bearer admin-token")
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != test.expectedStatus {
+				t.Errorf("got status %v, expected %v, body: %v", w.Code, test.expectedStatus, w.Body.String())
+			}
+		})
+	}
+}
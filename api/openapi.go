@@ -0,0 +1,106 @@
+package api
+
+import (
+	"strings"
+)
+
+func fieldSchema(field fieldT) map[string]interface{} {
+	schema := map[string]interface{}{"type": "string"}
+	if field.kind == "integer" {
+		schema["type"] = "integer"
+		schema["minimum"] = 0
+	}
+	if len(field.enum) > 0 {
+		schema["enum"] = field.enum
+	}
+	return schema
+}
+
+// OpenAPI returns the OpenAPI 3 description of the api, generated from the route table
+func (s *ServerT) OpenAPI() map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, route := range s.routes {
+		path, ok := paths[route.path].(map[string]interface{})
+		if !ok {
+			path = map[string]interface{}{}
+			paths[route.path] = path
+		}
+
+		parameters := []interface{}{}
+		for _, segment := range strings.Split(route.path, "/") {
+			if strings.HasPrefix(segment, "{") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     strings.Trim(segment, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, field := range route.query {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        field.name,
+				"in":          "query",
+				"required":    field.required,
+				"description": field.help,
+				"schema":      fieldSchema(field),
+			})
+		}
+
+		operation := map[string]interface{}{
+			"summary":     route.summary,
+			"description": "Requires the " + route.role + " role",
+			"parameters":  parameters,
+			"security":    []interface{}{map[string]interface{}{"token": []string{}}},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{"description": "Success"},
+				"400": map[string]interface{}{"description": "Invalid request"},
+				"401": map[string]interface{}{"description": "Missing or invalid token"},
+				"403": map[string]interface{}{"description": "The token doesn't have the required role"},
+				"502": map[string]interface{}{"description": "Haproxy returned an error"},
+			},
+		}
+		if len(route.body) > 0 {
+			properties := map[string]interface{}{}
+			required := []string{}
+			for _, field := range route.body {
+				properties[field.name] = fieldSchema(field)
+				if field.required {
+					required = append(required, field.name)
+				}
+			}
+			schema := map[string]interface{}{
+				"type":                 "object",
+				"properties":           properties,
+				"additionalProperties": false,
+			}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schema},
+				},
+			}
+		}
+		path[strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   s.Title,
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+			},
+		},
+	}
+}
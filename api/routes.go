@@ -0,0 +1,332 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// routes is the route table of the api, the OpenAPI description is generated from it
+var routes = []routeT{
+	{
+		method:  http.MethodGet,
+		path:    "/info",
+		role:    RoleRead,
+		summary: "The process information (show info)",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return h.ShowInfo()
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/stats",
+		role:    RoleRead,
+		summary: "The counters of all proxies and servers (show stat)",
+		query: []fieldT{
+			{name: "proxy", help: "Only return the rows of this proxy"},
+			{name: "type", enum: []string{"frontend", "backend", "server", "listener"}, help: "Only return the rows of this type"},
+		},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			stats, err := h.ShowStat()
+			if err != nil {
+				return nil, err
+			}
+			types := map[string]string{"frontend": "0", "backend": "1", "server": "2", "listener": "3"}
+			toReturn := []map[string]string{}
+			for _, row := range stats {
+				if r.str("proxy") != "" && row["pxname"] != r.str("proxy") {
+					continue
+				}
+				if r.str("type") != "" && row["type"] != types[r.str("type")] {
+					continue
+				}
+				toReturn = append(toReturn, row)
+			}
+			return toReturn, nil
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/frontends",
+		role:    RoleRead,
+		summary: "The stats of all frontends",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			stats, err := h.ShowStat()
+			if err != nil {
+				return nil, err
+			}
+			toReturn := []map[string]string{}
+			for _, row := range stats {
+				if row["type"] == "0" {
+					toReturn = append(toReturn, row)
+				}
+			}
+			return toReturn, nil
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/frontends/{frontend}/state",
+		role:    RoleAdmin,
+		summary: "Enable or disable a frontend",
+		body:    []fieldT{{name: "state", required: true, enum: []string{"enabled", "disabled"}}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			if r.str("state") == "enabled" {
+				return nil, h.EnableFrontend(r.str("frontend"))
+			}
+			return nil, h.DisableFrontend(r.str("frontend"))
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/frontends/{frontend}/maxconn",
+		role:    RoleAdmin,
+		summary: "Change the maxconn of a frontend",
+		body:    []fieldT{{name: "maxconn", kind: "integer", required: true}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.SetMaxconnFrontend(r.str("frontend"), r.uint("maxconn"))
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/backends",
+		role:    RoleRead,
+		summary: "The names of all backends (show backend)",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return h.ShowBackend()
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/servers",
+		role:    RoleRead,
+		summary: "The state of all servers (show servers state)",
+		query:   []fieldT{{name: "backend", help: "Only return the servers of this backend"}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			if r.str("backend") != "" {
+				return h.ShowServersState(r.str("backend"))
+			}
+			return h.ShowServersState()
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/servers/{backend}/{server}/weight",
+		role:    RoleRead,
+		summary: "The current and initial weight of a server",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			weight, err := h.GetWeight(r.str("backend"), r.str("server"))
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{"weight": weight}, nil
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/servers/{backend}/{server}/weight",
+		role:    RoleAdmin,
+		summary: "Change the weight of a server, can also be a percentage of the initial weight like 50%",
+		body:    []fieldT{{name: "weight", required: true}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.SetWeight(r.str("backend"), r.str("server"), r.str("weight"))
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/servers/{backend}/{server}/state",
+		role:    RoleAdmin,
+		summary: "Change the admin state of a server",
+		body:    []fieldT{{name: "state", required: true, enum: []string{"ready", "drain", "maint"}}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.Server(r.str("backend"), r.str("server")).State(r.str("state"))
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/servers/{backend}/{server}/health",
+		role:    RoleAdmin,
+		summary: "Force the health status of a server",
+		body:    []fieldT{{name: "health", required: true, enum: []string{"up", "stopping", "down"}}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.Server(r.str("backend"), r.str("server")).Health(r.str("health"))
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/servers/{backend}/{server}/addr",
+		role:    RoleAdmin,
+		summary: "Change the address and optionally the port of a server",
+		body:    []fieldT{{name: "addr", required: true}, {name: "port", kind: "integer"}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			if r.str("port") != "" {
+				// Addr adds the "port" keyword, without it haproxy ignores the port
+				return nil, h.Server(r.str("backend"), r.str("server")).Addr(r.str("addr"), r.str("port"))
+			}
+			return nil, h.Server(r.str("backend"), r.str("server")).Addr(r.str("addr"))
+		},
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/servers/{backend}/{server}/sessions",
+		role:    RoleAdmin,
+		summary: "Shut down all sessions of a server",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.ShutdownSessionsServer(r.str("backend"), r.str("server"))
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/sessions",
+		role:    RoleRead,
+		summary: "The current sessions (show sess)",
+		query: []fieldT{
+			{name: "source", help: "An IP or CIDR the source must match"},
+			{name: "frontend"},
+			{name: "backend"},
+			{name: "server"},
+		},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			f := haproxysocket.SessFilterT{
+				Frontend: r.str("frontend"),
+				Backend:  r.str("backend"),
+				Server:   r.str("server"),
+			}
+			if r.str("source") != "" {
+				f.Sources = []string{r.str("source")}
+			}
+			return h.QuerySess(f)
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/sessions/{id}",
+		role:    RoleRead,
+		summary: "All details of a session",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			details, err := h.ShowSessDetail(r.str("id"))
+			if err != nil {
+				return nil, err
+			}
+			if len(details) == 0 {
+				return nil, apiError{http.StatusNotFound, "session not found"}
+			}
+			return details[0], nil
+		},
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/sessions/{id}",
+		role:    RoleAdmin,
+		summary: "Shut down a session",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.ShutdownSession(r.str("id"))
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/maps/{map}",
+		role:    RoleRead,
+		summary: "The entries of a map, map is the file name (with / escaped as %2F) or #<id> (escaped as %23)",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return h.ShowMap(r.str("map"))
+		},
+	},
+	{
+		method:  http.MethodPost,
+		path:    "/maps/{map}",
+		role:    RoleAdmin,
+		summary: "Add an entry to a map",
+		body:    []fieldT{{name: "key", required: true}, {name: "value", required: true}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.AddMap(r.str("map"), r.str("key"), r.str("value"))
+		},
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/maps/{map}",
+		role:    RoleAdmin,
+		summary: "Delete all entries of a map",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.ClearMap(r.str("map"))
+		},
+	},
+	{
+		method:  http.MethodPut,
+		path:    "/maps/{map}/{key}",
+		role:    RoleAdmin,
+		summary: "Change the value of a map entry, key can also be #<ref>",
+		body:    []fieldT{{name: "value", required: true}},
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.SetMap(r.str("map"), r.str("key"), r.str("value"))
+		},
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/maps/{map}/{key}",
+		role:    RoleAdmin,
+		summary: "Delete a map entry, key can also be #<ref>",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return nil, h.DelMap(r.str("map"), r.str("key"))
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/acls/{acl}",
+		role:    RoleRead,
+		summary: "The entries of an acl, acl is the file name (with / escaped as %2F) or #<id> (escaped as %23)",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return h.ShowACL(r.str("acl"))
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/tables",
+		role:    RoleRead,
+		summary: "The usage of all stick tables",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			return h.ShowTables()
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/tables/{table}",
+		role:    RoleRead,
+		summary: "The entries of a stick table",
+		handler: func(h *haproxysocket.HaproxyInstace, r *requestT) (interface{}, error) {
+			table, entries, err := h.ShowTable(r.str("table"))
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"table": table, "entries": entries}, nil
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/stream",
+		role:    RoleRead,
+		summary: "Streams the stats deltas and state change events as server-sent events (text/event-stream), or over a websocket if the client requests an upgrade",
+		query: []fieldT{
+			{name: "proxy", help: "Comma separated list of proxies to receive the stats and events of"},
+			{name: "server", help: "Comma separated list of servers to receive the stats and events of"},
+			{name: "token", help: "The api token, for clients that can't set the Authorization header"},
+		},
+		stream: true,
+	},
+}
+
+// init checks the route table, every route that changes haproxy must require the admin role
+func init() {
+	for _, route := range routes {
+		if route.role != RoleRead && route.role != RoleAdmin {
+			panic(errors.New("route " + route.method + " " + route.path + " has no valid role"))
+		}
+		if route.handler == nil && !route.stream {
+			panic(errors.New("route " + route.method + " " + route.path + " has no handler"))
+		}
+		if route.method != http.MethodGet && route.role != RoleAdmin {
+			panic(errors.New("route " + route.method + " " + route.path + " changes haproxy and must require the admin role"))
+		}
+	}
+}
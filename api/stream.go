@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// The "show stat" values that are sent next to the deltas and rates
+var streamValues = []string{"status", "weight", "scur", "qcur", "check_status"}
+
+// StreamRowT is a "show stat" row in a stats message
+type StreamRowT struct {
+	Proxy  string             `json:"proxy"`
+	Server string             `json:"server"`
+	Values map[string]string  `json:"values"`
+	Deltas map[string]uint64  `json:"deltas"`
+	Rates  map[string]float64 `json:"rates"`
+}
+
+// StreamMessageT is a message sent to the subscribers
+type StreamMessageT struct {
+	Type  string                     `json:"type"` // stats or event
+	Time  time.Time                  `json:"time"`
+	Reset bool                       `json:"reset,omitempty"` // Only for stats, haproxy restarted since the previous message
+	Rows  []StreamRowT               `json:"rows,omitempty"`  // Only for stats
+	Event *haproxysocket.WatchEventT `json:"event,omitempty"` // Only for event
+}
+
+// subscriberT is a single client
+type subscriberT struct {
+	proxies  []string
+	servers  []string
+	messages chan StreamMessageT
+}
+
+func (sub *subscriberT) matches(proxy, server string) bool {
+	if len(sub.proxies) > 0 && !inList(proxy, sub.proxies) {
+		return false
+	}
+	if len(sub.servers) > 0 && !inList(server, sub.servers) {
+		return false
+	}
+	return true
+}
+
+// filter returns the message with only the rows the subscriber is interested in, false if nothing is left
+// Errors are sent to all subscribers
+func (sub *subscriberT) filter(message StreamMessageT) (StreamMessageT, bool) {
+	if message.Event != nil && message.Event.Kind == haproxysocket.WatchError {
+		return message, true
+	}
+	if message.Event != nil {
+		return message, sub.matches(message.Event.Proxy, message.Event.Server)
+	}
+	rows := []StreamRowT{}
+	for _, row := range message.Rows {
+		if sub.matches(row.Proxy, row.Server) {
+			rows = append(rows, row)
+		}
+	}
+	message.Rows = rows
+	return message, len(rows) > 0
+}
+
+// StreamT streams the stats deltas and state change events to all subscribers
+// Haproxy is only polled while there are subscribers and all subscribers share the same polls
+type StreamT struct {
+	h *haproxysocket.HaproxyInstace
+	m sync.Mutex
+
+	Interval time.Duration // The time between polls, defaults to 2 seconds
+
+	subscribers map[*subscriberT]bool
+	stop        context.CancelFunc
+}
+
+// NewStream creates a stream for a haproxy instance
+func NewStream(h *haproxysocket.HaproxyInstace) *StreamT {
+	return &StreamT{
+		h:           h,
+		subscribers: map[*subscriberT]bool{},
+	}
+}
+
+func (s *StreamT) subscribe(sub *subscriberT) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.subscribers[sub] = true
+	if s.stop == nil {
+		ctx, stop := context.WithCancel(context.Background())
+		s.stop = stop
+		go s.poll(ctx)
+	}
+}
+
+func (s *StreamT) unsubscribe(sub *subscriberT) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.subscribers, sub)
+	if len(s.subscribers) == 0 && s.stop != nil {
+		s.stop()
+		s.stop = nil
+	}
+}
+
+// broadcast sends a message to all subscribers, slow subscribers miss the message
+func (s *StreamT) broadcast(message StreamMessageT) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for sub := range s.subscribers {
+		filtered, ok := sub.filter(message)
+		if !ok {
+			continue
+		}
+		select {
+		case sub.messages <- filtered:
+		default:
+		}
+	}
+}
+
+func (s *StreamT) poll(ctx context.Context) {
+	interval := s.Interval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	poller := s.h.StatPoller()
+	watcher := s.h.Watcher()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		snapshot := poller.Poll()
+		if snapshot.Err != nil {
+			s.broadcast(StreamMessageT{
+				Type:  "event",
+				Time:  snapshot.Time,
+				Event: &haproxysocket.WatchEventT{Time: snapshot.Time, Kind: haproxysocket.WatchError, Message: snapshot.Error},
+			})
+		} else {
+			message := StreamMessageT{Type: "stats", Time: snapshot.Time, Reset: snapshot.Reset, Rows: []StreamRowT{}}
+			for _, row := range snapshot.Rows {
+				values := map[string]string{}
+				for _, key := range streamValues {
+					values[key] = row.Values[key]
+				}
+				message.Rows = append(message.Rows, StreamRowT{
+					Proxy:  row.Proxy,
+					Server: row.Server,
+					Values: values,
+					Deltas: row.Deltas,
+					Rates:  row.Rates,
+				})
+			}
+			s.broadcast(message)
+		}
+
+		events, err := watcher.Check()
+		if err == nil {
+			for i := range events {
+				s.broadcast(StreamMessageT{Type: "event", Time: events[i].Time, Event: &events[i]})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP streams the messages using server-sent events or a websocket if the client requests an upgrade
+// The query parameters proxy and server are optional comma separated lists to filter the messages
+func (s *StreamT) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sub := &subscriberT{
+		proxies:  splitList(r.URL.Query().Get("proxy")),
+		servers:  splitList(r.URL.Query().Get("server")),
+		messages: make(chan StreamMessageT, 16),
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		s.serveWebSocket(w, r, sub)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, apiError{http.StatusInternalServerError, "streaming is not supported"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s.subscribe(sub)
+	defer s.unsubscribe(sub)
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err := w.Write([]byte(": keep-alive\n\n"))
+			if err != nil {
+				return
+			}
+		case message := <-sub.messages:
+			data, err := json.Marshal(message)
+			if err != nil {
+				continue
+			}
+			_, err = w.Write([]byte("event: " + message.Type + "\ndata: " + string(data) + "\n\n"))
+			if err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func inList(item string, list []string) bool {
+	for _, listItem := range list {
+		if item == listItem {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(in string) []string {
+	toReturn := []string{}
+	for _, item := range strings.Split(in, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			toReturn = append(toReturn, item)
+		}
+	}
+	return toReturn
+}
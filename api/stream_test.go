@@ -0,0 +1,106 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+func TestSubscriberFilter(t *testing.T) {
+	statsMessage := StreamMessageT{
+		Type: "stats",
+		Rows: []StreamRowT{
+			{Proxy: "be_app", Server: "BACKEND"},
+			{Proxy: "be_app", Server: "app1"},
+			{Proxy: "be_static", Server: "static1"},
+		},
+	}
+	eventMessage := func(kind, proxy, server string) StreamMessageT {
+		return StreamMessageT{Type: "event", Event: &haproxysocket.WatchEventT{Kind: kind, Proxy: proxy, Server: server}}
+	}
+
+	tests := []struct {
+		name         string
+		sub          subscriberT
+		message      StreamMessageT
+		expectedOk   bool
+		expectedRows []StreamRowT
+	}{
+		{
+			name:         "no filter",
+			sub:          subscriberT{},
+			message:      statsMessage,
+			expectedOk:   true,
+			expectedRows: statsMessage.Rows,
+		},
+		{
+			name:       "proxy filter",
+			sub:        subscriberT{proxies: []string{"be_app"}},
+			message:    statsMessage,
+			expectedOk: true,
+			expectedRows: []StreamRowT{
+				{Proxy: "be_app", Server: "BACKEND"},
+				{Proxy: "be_app", Server: "app1"},
+			},
+		},
+		{
+			name:         "proxy and server filter",
+			sub:          subscriberT{proxies: []string{"be_app", "be_static"}, servers: []string{"static1"}},
+			message:      statsMessage,
+			expectedOk:   true,
+			expectedRows: []StreamRowT{{Proxy: "be_static", Server: "static1"}},
+		},
+		{
+			name:         "nothing left",
+			sub:          subscriberT{proxies: []string{"be_other"}},
+			message:      statsMessage,
+			expectedOk:   false,
+			expectedRows: []StreamRowT{},
+		},
+		{
+			name:       "matching event",
+			sub:        subscriberT{proxies: []string{"be_app"}},
+			message:    eventMessage(haproxysocket.WatchServerState, "be_app", "app1"),
+			expectedOk: true,
+		},
+		{
+			name:       "other event",
+			sub:        subscriberT{proxies: []string{"be_app"}},
+			message:    eventMessage(haproxysocket.WatchServerState, "be_static", "static1"),
+			expectedOk: false,
+		},
+		{
+			name:       "errors are sent to everyone",
+			sub:        subscriberT{proxies: []string{"be_app"}},
+			message:    eventMessage(haproxysocket.WatchError, "", ""),
+			expectedOk: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filtered, ok := test.sub.filter(test.message)
+			if ok != test.expectedOk {
+				t.Errorf("got ok %v, expected %v", ok, test.expectedOk)
+			}
+			if test.message.Event == nil && !reflect.DeepEqual(filtered.Rows, test.expectedRows) {
+				t.Errorf("got rows %+v, expected %+v", filtered.Rows, test.expectedRows)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		"":                {},
+		"be_app":          {"be_app"},
+		"be_app, be_web,": {"be_app", "be_web"},
+	}
+	for in, expected := range tests {
+		got := splitList(in)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("splitList(%q) = %v, expected %v", in, got, expected)
+		}
+	}
+}
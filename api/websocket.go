@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A minimal websocket server (RFC 6455) that only sends text messages to the client
+// Messages from the client are read and discarded, except for close and ping
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// The websocket opcodes
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+func (s *StreamT) serveWebSocket(w http.ResponseWriter, r *http.Request, sub *subscriberT) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || !strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		writeError(w, apiError{http.StatusBadRequest, "invalid websocket handshake"})
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, apiError{http.StatusInternalServerError, "websockets are not supported"})
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	_, err = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		return
+	}
+
+	s.subscribe(sub)
+	defer s.unsubscribe(sub)
+
+	// The reader reports the pings and stops when the client closes the connection
+	closed := make(chan struct{})
+	pings := make(chan []byte, 1)
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := readWSFrame(buf.Reader)
+			if err != nil || opcode == wsClose {
+				return
+			}
+			if opcode == wsPing {
+				select {
+				case pings <- payload:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			writeWSFrame(conn, wsClose, []byte{})
+			return
+		case payload := <-pings:
+			err = writeWSFrame(conn, wsPong, payload)
+		case message := <-sub.messages:
+			data, marshalErr := json.Marshal(message)
+			if marshalErr != nil {
+				continue
+			}
+			err = writeWSFrame(conn, wsText, data)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeWSFrame(conn net.Conn, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode} // FIN is always set, messages are never fragmented
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := conn.Write(append(header, payload...))
+	return err
+}
+
+// readWSFrame reads a single frame from the client, the payload is unmasked
+func readWSFrame(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(r, extended)
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		_, err = io.ReadFull(r, extended)
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if length > 1<<20 {
+		return 0, nil, io.ErrShortBuffer
+	}
+	mask := make([]byte, 4)
+	if masked {
+		_, err = io.ReadFull(r, mask)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
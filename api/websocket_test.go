@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// maskedFrame builds a frame like a client sends it, clients must always mask the payload
+func maskedFrame(opcode byte, payload []byte, mask [4]byte) []byte {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestReadWSFrame(t *testing.T) {
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	tests := []struct {
+		name    string
+		frame   []byte
+		opcode  byte
+		payload []byte
+	}{
+		{
+			name:    "masked text",
+			frame:   maskedFrame(wsText, []byte("Hello"), mask),
+			opcode:  wsText,
+			payload: []byte("Hello"),
+		},
+		{
+			name:    "rfc 6455 example",
+			frame:   []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58},
+			opcode:  wsText,
+			payload: []byte("Hello"),
+		},
+		{
+			name:    "unmasked",
+			frame:   []byte{0x89, 0x02, 'h', 'i'},
+			opcode:  wsPing,
+			payload: []byte("hi"),
+		},
+		{
+			name:    "16 bit length",
+			frame:   maskedFrame(wsText, bytes.Repeat([]byte("a"), 300), mask),
+			opcode:  wsText,
+			payload: bytes.Repeat([]byte("a"), 300),
+		},
+		{
+			name:    "64 bit length",
+			frame:   maskedFrame(wsText, bytes.Repeat([]byte("b"), 70000), mask),
+			opcode:  wsText,
+			payload: bytes.Repeat([]byte("b"), 70000),
+		},
+		{
+			name:    "close",
+			frame:   maskedFrame(wsClose, []byte{}, mask),
+			opcode:  wsClose,
+			payload: []byte{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opcode, payload, err := readWSFrame(bufio.NewReader(bytes.NewReader(test.frame)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opcode != test.opcode {
+				t.Errorf("got opcode %v, expected %v", opcode, test.opcode)
+			}
+			if !bytes.Equal(payload, test.payload) {
+				t.Errorf("got payload of %v bytes, expected %v bytes", len(payload), len(test.payload))
+			}
+		})
+	}
+}
+
+func TestReadWSFrameErrors(t *testing.T) {
+	tooLarge := []byte{0x81, 0x7f, 0, 0, 0, 0, 0, 0x20, 0, 0}
+	_, _, err := readWSFrame(bufio.NewReader(bytes.NewReader(tooLarge)))
+	if err == nil {
+		t.Errorf("expected an error for a frame larger than 1MB")
+	}
+
+	truncated := maskedFrame(wsText, []byte("Hello"), [4]byte{1, 2, 3, 4})
+	_, _, err = readWSFrame(bufio.NewReader(bytes.NewReader(truncated[:len(truncated)-2])))
+	if err == nil {
+		t.Errorf("expected an error for a truncated frame")
+	}
+}
+
+func TestWriteWSFrame(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte("c"), size)
+		server, client := net.Pipe()
+		go func() {
+			writeWSFrame(server, wsText, payload)
+			server.Close()
+		}()
+		opcode, got, err := readWSFrame(bufio.NewReader(client))
+		client.Close()
+		if err != nil {
+			t.Fatalf("size %v: unexpected error: %v", size, err)
+		}
+		if opcode != wsText || !bytes.Equal(got, payload) {
+			t.Errorf("size %v: got opcode %v with %v bytes", size, opcode, len(got))
+		}
+	}
+}
+
+// dialWebSocket connects to the stream and does the websocket handshake
+func dialWebSocket(t *testing.T, s *StreamT) (net.Conn, *bufio.Reader) {
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("unable to send the handshake: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("unable to read the handshake response: %v", err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %v, expected %v", res.StatusCode, http.StatusSwitchingProtocols)
+	}
+	// The accept value of the example key from RFC 6455
+	if accept := res.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("got Sec-WebSocket-Accept %v", accept)
+	}
+	return conn, reader
+}
+
+// nextControlFrame reads frames until a control frame, the text frames with stats and events are skipped
+func nextControlFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	for {
+		opcode, payload, err := readWSFrame(reader)
+		if err != nil {
+			t.Fatalf("unable to read a frame: %v", err)
+		}
+		if opcode != wsText {
+			return opcode, payload
+		}
+	}
+}
+
+// unreachableInstance is an instance where every command fails, the stream reports the errors as events
+func unreachableInstance() *haproxysocket.HaproxyInstace {
+	h := haproxysocket.New("unix", "/nonexistent.sock")
+	h.Interceptors = []haproxysocket.InterceptorT{func(c *haproxysocket.CommandT, next func() (string, error)) (string, error) {
+		return "", errors.New("haproxy is not running")
+	}}
+	return h
+}
+
+func TestWebSocket(t *testing.T) {
+	s := NewStream(unreachableInstance())
+	s.Interval = time.Hour
+	conn, reader := dialWebSocket(t, s)
+	mask := [4]byte{0xa1, 0xb2, 0xc3, 0xd4}
+
+	_, err := conn.Write(maskedFrame(wsPing, []byte("ping payload"), mask))
+	if err != nil {
+		t.Fatalf("unable to send a ping: %v", err)
+	}
+	opcode, payload := nextControlFrame(t, reader)
+	if opcode != wsPong || string(payload) != "ping payload" {
+		t.Errorf("got opcode %v with payload %q, expected a pong with the ping payload", opcode, payload)
+	}
+
+	_, err = conn.Write(maskedFrame(wsClose, []byte{}, mask))
+	if err != nil {
+		t.Fatalf("unable to send a close: %v", err)
+	}
+	opcode, _ = nextControlFrame(t, reader)
+	if opcode != wsClose {
+		t.Errorf("got opcode %v, expected a close", opcode)
+	}
+	_, _, err = readWSFrame(reader)
+	if err == nil {
+		t.Errorf("expected the server to close the connection after the close frame")
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	s := NewStream(unreachableInstance())
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Connection", "Upgrade")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %v for a handshake without key, expected %v", w.Code, http.StatusBadRequest)
+	}
+}
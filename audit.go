@@ -0,0 +1,255 @@
+package haproxysocket
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntryT is a record of a single command that changed haproxy
+type AuditEntryT struct {
+	Time     time.Time     `json:"time"`
+	Actor    string        `json:"actor,omitempty"` // Who executed the command, set using As
+	Network  string        `json:"network"`
+	Address  string        `json:"address"`
+	Worker   string        `json:"worker,omitempty"` // The master cli route, for example @1 or @!1234
+	Command  string        `json:"command"`          // The payload of "<<" commands is not included as it can contain private keys
+	Previous string        `json:"previous,omitempty"`
+	Result   string        `json:"result"` // The output of haproxy, most commands return an empty result on success
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// auditVerbs are the first words of the commands that change haproxy
+var auditVerbs = []string{"set", "add", "del", "clear", "disable", "enable", "shutdown", "commit", "abort", "new", "prepare", "update", "reload"}
+
+// The output of "get map" looks like:
+// type=str, case=sensitive, found=yes, idx=tree, key="key1", value="value1", type="str"
+var auditMapValueRegex = regexp.MustCompile(`value="((?:[^"\\]|\\.)*)"`)
+
+// As returns a copy of the instance that records actor as the executor of the commands in the audit log
+func (h *HaproxyInstace) As(actor string) *HaproxyInstace {
+	toReturn := *h
+	toReturn.Actor = actor
+	return &toReturn
+}
+
+// IsMutating returns true if a query changes haproxy
+func IsMutating(query string) bool {
+	fields := strings.Fields(query)
+	return len(fields) > 0 && inList(fields[0], auditVerbs)
+}
+
+// raw returns a copy of the instance without interceptors and audit hook
+// It's used to look up the previous values so these lookups don't go through the interceptors while the audited command is running
+func (h *HaproxyInstace) raw() *HaproxyInstace {
+	toReturn := *h
+	toReturn.Audit = nil
+	toReturn.Interceptors = nil
+	return &toReturn
+}
+
+// auditQ executes a query that changes haproxy through the interceptors and sends it to the audit hook
+func (h *HaproxyInstace) auditQ(query string) (string, error) {
+	command := query
+	if idx := strings.Index(command, " <<"); idx != -1 {
+		command = command[:idx] + " <<"
+	}
+	entry := AuditEntryT{
+		Actor:    h.Actor,
+		Network:  h.Network,
+		Address:  h.Address,
+		Worker:   h.prefix,
+		Command:  command,
+		Previous: h.raw().auditPrevious(strings.Fields(command)),
+	}
+
+	entry.Time = time.Now()
+	out, err := h.intercept(query)
+	entry.Duration = time.Since(entry.Time)
+	entry.Result = out
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	h.Audit(entry)
+	return out, err
+}
+
+// auditPrevious returns the value a command is going to change, empty if unknown
+func (h *HaproxyInstace) auditPrevious(fields []string) string {
+	arg := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	command := arg(0) + " " + arg(1)
+
+	switch {
+	case command == "set weight":
+		weight, _ := h.GetWeight(splitServer(arg(2)))
+		return weight
+	case command == "set server":
+		return h.auditServer(arg(2), arg(3))
+	case command == "set maxconn" && arg(2) == "global":
+		return h.auditInfo("Maxconn")
+	case command == "set maxconn" && arg(2) == "frontend":
+		return h.auditFrontend(arg(3), "slim")
+	case command == "set rate-limit":
+		keys := map[string]string{
+			"connections":      "ConnRateLimit",
+			"sessions":         "SessRateLimit",
+			"ssl-sessions":     "SslRateLimit",
+			"http-compression": "CompressBpsRateLim",
+		}
+		return h.auditInfo(keys[arg(2)])
+	case arg(1) == "frontend" && inList(arg(0), []string{"disable", "enable", "shutdown"}):
+		return h.auditFrontend(arg(2), "status")
+	case command == "set map" || command == "del map":
+		// "get map" matches a sample so it can't look up entries by their #<ref>
+		if strings.HasPrefix(arg(3), "#") {
+			return ""
+		}
+		out, err := h.q("get map " + arg(2) + " " + arg(3))
+		if err != nil || !strings.Contains(out, "found=yes") {
+			return ""
+		}
+		match := auditMapValueRegex.FindStringSubmatch(out)
+		if match == nil {
+			return ""
+		}
+		return match[1]
+	case command == "shutdown session":
+		sessions, err := h.ShowSessDetail(arg(2))
+		if err != nil || len(sessions) == 0 {
+			return ""
+		}
+		session := sessions[0]
+		return session.Source + " " + session.Frontend.Name + "/" + session.Backend.Name + "/" + session.Server.Name
+	}
+	return ""
+}
+
+func splitServer(backendAndServer string) (string, string) {
+	parts := strings.SplitN(backendAndServer, "/", 2)
+	if len(parts) != 2 {
+		return backendAndServer, ""
+	}
+	return parts[0], parts[1]
+}
+
+// auditServer returns the current value of a "set server" setting
+func (h *HaproxyInstace) auditServer(backendAndServer, setting string) string {
+	backend, server := splitServer(backendAndServer)
+	if setting == "weight" {
+		weight, _ := h.GetWeight(backend, server)
+		return weight
+	}
+	if backend == "" {
+		return ""
+	}
+	states, err := h.ShowServersState(backend)
+	if err != nil {
+		return ""
+	}
+	for _, state := range states {
+		if state.Server != server {
+			continue
+		}
+		switch setting {
+		case "state":
+			if state.AdminState.Maint() {
+				return "maint"
+			}
+			if state.AdminState.Drain() {
+				return "drain"
+			}
+			return "ready"
+		case "health":
+			return state.OpState.String()
+		case "addr":
+			return state.Addr + " port " + strconv.Itoa(state.Port)
+		case "fqdn":
+			return state.FQDN
+		case "agent-addr":
+			return state.AgentAddr
+		case "check-port":
+			return strconv.Itoa(state.CheckPort)
+		}
+	}
+	return ""
+}
+
+func (h *HaproxyInstace) auditInfo(key string) string {
+	if key == "" {
+		return ""
+	}
+	info, err := h.ShowInfo()
+	if err != nil {
+		return ""
+	}
+	return info[key]
+}
+
+func (h *HaproxyInstace) auditFrontend(frontend, field string) string {
+	stats, err := h.ShowStat()
+	if err != nil {
+		return ""
+	}
+	for _, row := range stats {
+		if row["pxname"] == frontend && row["type"] == "0" {
+			return row[field]
+		}
+	}
+	return ""
+}
+
+// AuditWriterT writes the audit entries as JSON lines, use its Record method as audit hook:
+// h.Audit = haproxysocket.NewAuditWriter(os.Stdout).Record
+type AuditWriterT struct {
+	m      sync.Mutex
+	w      io.Writer
+	closer io.Closer
+
+	OnError func(err error) // Called when an entry can't be written
+}
+
+// NewAuditWriter creates an audit sink that writes to w
+func NewAuditWriter(w io.Writer) *AuditWriterT {
+	return &AuditWriterT{w: w}
+}
+
+// NewAuditFile creates an audit sink that appends to a file, the file is created if it doesn't exist
+func NewAuditFile(path string) (*AuditWriterT, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditWriterT{w: f, closer: f}, nil
+}
+
+// Record writes a single entry
+func (a *AuditWriterT) Record(entry AuditEntryT) {
+	line, err := json.Marshal(entry)
+	if err == nil {
+		a.m.Lock()
+		_, err = a.w.Write(append(line, '\n'))
+		a.m.Unlock()
+	}
+	if err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+// Close closes the file of an audit sink created using NewAuditFile
+func (a *AuditWriterT) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
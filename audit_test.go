@@ -0,0 +1,32 @@
+package haproxysocket
+
+import "testing"
+
+func TestIsMutating(t *testing.T) {
+	tests := map[string]bool{
+		"show stat":                             false,
+		"show map #0":                           false,
+		"get map #0 example.com":                false,
+		"":                                      false,
+		"kill session 0x55d4c7e4b000":           false, // Not a runtime api command, sessions are killed using shutdown
+		"shutdown session 0x55d4c7e4b000":       true,
+		"set server be_app/app1 state drain":    true,
+		"add map #0 example.com be_app":         true,
+		"del acl #0 10.0.0.1":                   true,
+		"clear counters all":                    true,
+		"set ssl cert foo.pem <<\n-----BEGIN":   true,
+		"commit ssl cert foo.pem":               true,
+		"reload":                                true,
+		"  disable server be_app/app1":          true,
+		"prepare map #0":                        true,
+		"new ssl ca-file ca.pem":                true,
+		"update ssl ocsp-response /etc/foo.pem": true,
+		"abort ssl cert foo.pem":                true,
+		"enable health be_app/app1":             true,
+	}
+	for query, expected := range tests {
+		if got := IsMutating(query); got != expected {
+			t.Errorf("IsMutating(%q) = %v, expected %v", query, got, expected)
+		}
+	}
+}
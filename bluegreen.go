@@ -0,0 +1,335 @@
+package haproxysocket
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BlueGreenSwitchT is a recorded switch between colors
+type BlueGreenSwitchT struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Time     time.Time         `json:"time"`
+	MapValue string            `json:"mapValue,omitempty"` // The map value before the switch
+	Servers  map[string]string `json:"servers,omitempty"`  // The server states before the switch, key is backend/server
+}
+
+// BlueGreenT switches traffic between 2 backends, create one using BlueGreen
+type BlueGreenT struct {
+	h *HaproxyInstace
+	m sync.Mutex
+
+	Blue  string // The blue backend
+	Green string // The green backend
+
+	// When Map is set the switch is done by writing the backend name into this map entry,
+	// otherwise all servers of the target backend are set to ready and the servers of the other backend to InactiveState
+	Map string
+	Key string
+
+	InactiveState string // The state of the inactive servers, "drain" (default) or "maint", with drain the health checks keep running
+	MinHealthy    int    // The minimal amount of healthy servers the target needs, defaults to 1
+	// CheckTimeout is how long Switch waits for the health checks of target servers that were in maint, defaults to 30 seconds
+	CheckTimeout time.Duration
+
+	History []BlueGreenSwitchT // All switches done, the last one is used for Rollback
+}
+
+// BlueGreen creates a blue/green switch between 2 backends
+func (h *HaproxyInstace) BlueGreen(blueBackend, greenBackend string) *BlueGreenT {
+	return &BlueGreenT{
+		h:       h,
+		Blue:    blueBackend,
+		Green:   greenBackend,
+		History: []BlueGreenSwitchT{},
+	}
+}
+
+// backend returns the backend of a color
+func (b *BlueGreenT) backend(color string) (string, error) {
+	switch color {
+	case "blue":
+		return b.Blue, nil
+	case "green":
+		return b.Green, nil
+	default:
+		return "", errors.New("color has wrong value, must be \"blue\" or \"green\"")
+	}
+}
+
+// Active returns the color that currently receives the traffic
+func (b *BlueGreenT) Active() (string, error) {
+	if b.Map != "" {
+		entries, err := b.h.ShowMap(b.Map)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			if entry.Key != b.Key {
+				continue
+			}
+			switch entry.Value {
+			case b.Blue:
+				return "blue", nil
+			case b.Green:
+				return "green", nil
+			}
+			return "", errors.New("map value " + entry.Value + " doesn't match the blue or green backend")
+		}
+		return "", errors.New("key " + b.Key + " not found in map " + b.Map)
+	}
+
+	states, err := b.adminStates()
+	if err != nil {
+		return "", err
+	}
+	blueReady := false
+	greenReady := false
+	for name, state := range states {
+		if state.Maint() || state.Drain() {
+			continue
+		}
+		if strings.HasPrefix(name, b.Blue+"/") {
+			blueReady = true
+		} else {
+			greenReady = true
+		}
+	}
+	switch {
+	case blueReady && !greenReady:
+		return "blue", nil
+	case greenReady && !blueReady:
+		return "green", nil
+	default:
+		return "", errors.New("unable to detect the active color, both or none of the backends have ready servers")
+	}
+}
+
+// Healthy checks if the backend of a color has enough healthy servers
+func (b *BlueGreenT) Healthy(color string) error {
+	backend, err := b.backend(color)
+	if err != nil {
+		return err
+	}
+	stats, err := b.h.ShowStat()
+	if err != nil {
+		return err
+	}
+
+	minHealthy := b.MinHealthy
+	if minHealthy == 0 {
+		minHealthy = 1
+	}
+	healthy := 0
+	for _, row := range stats {
+		if row["pxname"] != backend || row["svname"] == "BACKEND" || row["svname"] == "FRONTEND" {
+			continue
+		}
+		status := row["status"]
+		if status == "DRAIN" || status == "no check" || strings.HasPrefix(status, "UP") {
+			healthy++
+		}
+	}
+	if healthy < minHealthy {
+		return fmt.Errorf("backend %v has %v healthy servers, needs at least %v", backend, healthy, minHealthy)
+	}
+	return nil
+}
+
+// Switch sends all traffic to color ("blue" or "green") after verifying it's healthy
+// Servers of the target that are in maint have no running health checks, they are put in drain first
+// and Switch waits up to CheckTimeout for them to become healthy
+func (b *BlueGreenT) Switch(color string) error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	target, err := b.backend(color)
+	if err != nil {
+		return err
+	}
+
+	from, err := b.Active()
+	if err != nil {
+		// Not a problem, this can happen on the first switch
+		from = ""
+	}
+
+	record := BlueGreenSwitchT{
+		From: from,
+		To:   color,
+		Time: time.Now(),
+	}
+
+	if b.Map != "" {
+		err = b.Healthy(color)
+		if err != nil {
+			return err
+		}
+		entries, err := b.h.ShowMap(b.Map)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Key == b.Key {
+				record.MapValue = entry.Value
+			}
+		}
+		err = b.h.SetMap(b.Map, b.Key, target)
+		if err != nil {
+			return err
+		}
+		b.History = append(b.History, record)
+		return nil
+	}
+
+	record.Servers, err = b.serverStates()
+	if err != nil {
+		return err
+	}
+
+	waking := map[string]string{}
+	for name, state := range record.Servers {
+		if strings.HasPrefix(name, target+"/") && state == "maint" {
+			waking[name] = "drain"
+		}
+	}
+	if len(waking) > 0 {
+		err = b.setStates(waking)
+		if err == nil {
+			err = b.waitHealthy(color)
+		}
+	} else {
+		err = b.Healthy(color)
+	}
+	if err != nil {
+		return b.restore(record.Servers, err)
+	}
+
+	inactiveState := b.InactiveState
+	if inactiveState == "" {
+		inactiveState = "drain"
+	}
+	// First enable the target so there is never a moment without servers
+	wanted := map[string]string{}
+	for name := range record.Servers {
+		if strings.HasPrefix(name, target+"/") {
+			wanted[name] = "ready"
+		}
+	}
+	err = b.setStates(wanted)
+	if err != nil {
+		return b.restore(record.Servers, err)
+	}
+	wanted = map[string]string{}
+	for name := range record.Servers {
+		if !strings.HasPrefix(name, target+"/") {
+			wanted[name] = inactiveState
+		}
+	}
+	err = b.setStates(wanted)
+	if err != nil {
+		return b.restore(record.Servers, err)
+	}
+
+	b.History = append(b.History, record)
+	return nil
+}
+
+// waitHealthy waits until the backend of a color is healthy or CheckTimeout is reached
+func (b *BlueGreenT) waitHealthy(color string) error {
+	timeout := b.CheckTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := b.Healthy(color)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// restore sets the servers back to their states from before a failed switch, err is the reason of the failure
+func (b *BlueGreenT) restore(states map[string]string, err error) error {
+	restoreErr := b.setStates(states)
+	if restoreErr != nil {
+		return errors.New(err.Error() + ", restoring the server states also failed: " + restoreErr.Error())
+	}
+	return err
+}
+
+// Rollback restores the state from before the last switch
+func (b *BlueGreenT) Rollback() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if len(b.History) == 0 {
+		return errors.New("nothing to rollback")
+	}
+	last := b.History[len(b.History)-1]
+
+	var err error
+	if b.Map != "" {
+		if last.MapValue == "" {
+			return errors.New("no previous map value recorded")
+		}
+		err = b.h.SetMap(b.Map, b.Key, last.MapValue)
+	} else {
+		err = b.setStates(last.Servers)
+	}
+	if err != nil {
+		return err
+	}
+
+	b.History = b.History[:len(b.History)-1]
+	return nil
+}
+
+// adminStates returns the admin state flags of all servers from both backends, key is backend/server
+func (b *BlueGreenT) adminStates() (map[string]SrvAdminState, error) {
+	toReturn := map[string]SrvAdminState{}
+	for _, backend := range []string{b.Blue, b.Green} {
+		servers, err := b.h.ShowServersState(backend)
+		if err != nil {
+			return toReturn, err
+		}
+		for _, server := range servers {
+			toReturn[backend+"/"+server.Server] = server.AdminState
+		}
+	}
+	return toReturn, nil
+}
+
+// serverStates returns the state as set from the cli of all servers from both backends, key is backend/server
+func (b *BlueGreenT) serverStates() (map[string]string, error) {
+	toReturn := map[string]string{}
+	states, err := b.adminStates()
+	if err != nil {
+		return toReturn, err
+	}
+	for name, state := range states {
+		toReturn[name] = state.Forced()
+	}
+	return toReturn, nil
+}
+
+// setStates sets the state of every backend/server in states
+func (b *BlueGreenT) setStates(states map[string]string) error {
+	errs := []string{}
+	for name, state := range states {
+		parts := strings.SplitN(name, "/", 2)
+		err := b.h.Server(parts[0], parts[1]).State(state)
+		if err != nil {
+			errs = append(errs, name+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlueGreenStates(t *testing.T) {
+	// blue1 is drained and DOWN, "show stat" reports it as "DOWN" but it must stay drained
+	h := cannedInstance(t, map[string]string{
+		"show servers state be_blue": "1\n" + serversStateHeader + "\n" +
+			"3 be_blue 1 blue1 10.0.0.1 0 8 1 1 12 6 2 0 6 0 0 0 - 80 - 0 0 - - 0\n" +
+			"3 be_blue 2 blue2 10.0.0.2 2 1 1 1 12 6 3 4 6 0 0 0 - 80 - 0 0 - - 0\n",
+		"show servers state be_green": "1\n" + serversStateHeader + "\n" +
+			"4 be_green 1 green1 10.0.1.1 2 0 1 1 12 6 3 4 6 0 0 0 - 80 - 0 0 - - 0\n" +
+			"4 be_green 2 green2 10.0.1.2 2 4 1 1 12 6 3 4 6 0 0 0 - 80 - 0 0 - - 0\n",
+	})
+	b := h.BlueGreen("be_blue", "be_green")
+
+	states, err := b.serverStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"be_blue/blue1":   "drain",
+		"be_blue/blue2":   "maint",
+		"be_green/green1": "ready",
+		"be_green/green2": "ready", // Maintenance from the configuration isn't set from the cli
+	}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("got %v, expected %v", states, expected)
+	}
+
+	active, err := b.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active != "green" {
+		t.Errorf("got active color %v, expected green", active)
+	}
+}
+
+func TestBlueGreenRollback(t *testing.T) {
+	h := cannedInstance(t, map[string]string{
+		"set server be_blue/blue1 state drain":   "",
+		"set server be_green/green1 state ready": "",
+	})
+	b := h.BlueGreen("be_blue", "be_green")
+	b.History = []BlueGreenSwitchT{{
+		From: "green",
+		To:   "blue",
+		Servers: map[string]string{
+			"be_blue/blue1":   "drain",
+			"be_green/green1": "ready",
+		},
+	}}
+
+	err := b.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.History) != 0 {
+		t.Errorf("expected the switch to be removed from the history, got %v", b.History)
+	}
+}
@@ -0,0 +1,315 @@
+package haproxysocket
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sslFileCommand executes a ca-file or crl-file command that takes a file name and checks the result
+func (h *HaproxyInstace) sslFileCommand(command, file string, success ...string) error {
+	if file == "" {
+		return errors.New("file can't be empty")
+	}
+	out, err := h.q(command + " " + file)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, success...)
+}
+
+// SSLFileListT is the list of loaded CA or CRL files
+type SSLFileListT struct {
+	Transaction string     `json:"transaction"` // The file with an uncommitted transaction, empty if there is none
+	Files       []SSLFileT `json:"files"`
+}
+
+// SSLFileT is a loaded CA or CRL file
+type SSLFileT struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"` // The amount of certificates in a CA file, not set for CRL files
+}
+
+// showSSLFiles parses the output of "show ssl ca-file" and "show ssl crl-file"
+func (h *HaproxyInstace) showSSLFiles(command string) (SSLFileListT, error) {
+	toReturn := SSLFileListT{Files: []SSLFileT{}}
+	out, err := h.q(command)
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "#") {
+		return toReturn, errors.New(out)
+	}
+
+	// The output looks like:
+	// # transaction
+	// *cafile.crt - 2 certificate(s)
+	// # filename
+	// cafile.crt - 1 certificate(s)
+	section := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " - ", 2)
+		switch section {
+		case "transaction":
+			toReturn.Transaction = strings.TrimPrefix(parts[0], "*")
+		case "filename":
+			toAdd := SSLFileT{Name: parts[0]}
+			if len(parts) == 2 {
+				toAdd.Entries, _ = strconv.Atoi(strings.SplitN(parts[1], " ", 2)[0])
+			}
+			toReturn.Files = append(toReturn.Files, toAdd)
+		}
+	}
+	return toReturn, nil
+}
+
+// NewSSLCAFile create a new empty CA file that can be filled using SetSSLCAFile
+func (h *HaproxyInstace) NewSSLCAFile(file string) error {
+	return h.sslFileCommand("new ssl ca-file", file, "New CA file created")
+}
+
+// SetSSLCAFile replace the contents of a CA file in a transaction, the payload contains one or more PEM certificates
+func (h *HaproxyInstace) SetSSLCAFile(file, payload string) error {
+	if file == "" || payload == "" {
+		return errors.New("file and payload can't be empty")
+	}
+	out, err := h.qPayload("set ssl ca-file "+file, payload)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "Transaction created", "Transaction updated")
+}
+
+// CommitSSLCAFile commit the transaction of a CA file
+func (h *HaproxyInstace) CommitSSLCAFile(file string) error {
+	return h.sslFileCommand("commit ssl ca-file", file, "Success!")
+}
+
+// AbortSSLCAFile abort the transaction of a CA file
+func (h *HaproxyInstace) AbortSSLCAFile(file string) error {
+	return h.sslFileCommand("abort ssl ca-file", file, "Transaction aborted")
+}
+
+// DelSSLCAFile delete an unused CA file
+func (h *HaproxyInstace) DelSSLCAFile(file string) error {
+	return h.sslFileCommand("del ssl ca-file", file, "deleted!")
+}
+
+// UpdateSSLCAFile replaces the contents of a CA file and commits it, if something goes wrong the transaction is aborted
+func (h *HaproxyInstace) UpdateSSLCAFile(file, payload string) error {
+	err := h.SetSSLCAFile(file, payload)
+	if err != nil {
+		return err
+	}
+	err = h.CommitSSLCAFile(file)
+	if err != nil {
+		h.AbortSSLCAFile(file)
+		return err
+	}
+	return nil
+}
+
+// ShowSSLCAFiles list all loaded CA files and the current transaction
+func (h *HaproxyInstace) ShowSSLCAFiles() (SSLFileListT, error) {
+	return h.showSSLFiles("show ssl ca-file")
+}
+
+// SSLCAFileT are the details of a CA file
+type SSLCAFileT struct {
+	Filename     string     `json:"filename"`
+	Status       string     `json:"status"`
+	Certificates []SSLCertT `json:"certificates"`
+}
+
+// ShowSSLCAFile report the certificates of a CA file, use *<file> to show the uncommitted transaction
+func (h *HaproxyInstace) ShowSSLCAFile(file string) (SSLCAFileT, error) {
+	toReturn := SSLCAFileT{Certificates: []SSLCertT{}}
+	if file == "" {
+		return toReturn, errors.New("file can't be empty")
+	}
+	out, err := h.q("show ssl ca-file " + file)
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "Filename:") {
+		return toReturn, errors.New(out)
+	}
+
+	// Every certificate starts with "Certificate #<n>:"
+	var block []string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Certificate #"):
+			if block != nil {
+				toReturn.Certificates = append(toReturn.Certificates, parseSSLCert(block))
+			}
+			block = []string{}
+		case block != nil:
+			block = append(block, line)
+		case strings.HasPrefix(line, "Filename:"):
+			toReturn.Filename = strings.TrimSpace(strings.TrimPrefix(line, "Filename:"))
+		case strings.HasPrefix(line, "Status:"):
+			toReturn.Status = strings.TrimSpace(strings.TrimPrefix(line, "Status:"))
+		}
+	}
+	if block != nil {
+		toReturn.Certificates = append(toReturn.Certificates, parseSSLCert(block))
+	}
+
+	return toReturn, nil
+}
+
+// NewSSLCRLFile create a new empty CRL file that can be filled using SetSSLCRLFile
+func (h *HaproxyInstace) NewSSLCRLFile(file string) error {
+	return h.sslFileCommand("new ssl crl-file", file, "New CRL file created")
+}
+
+// SetSSLCRLFile replace the contents of a CRL file in a transaction, the payload contains one or more PEM CRLs
+func (h *HaproxyInstace) SetSSLCRLFile(file, payload string) error {
+	if file == "" || payload == "" {
+		return errors.New("file and payload can't be empty")
+	}
+	out, err := h.qPayload("set ssl crl-file "+file, payload)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "Transaction created", "Transaction updated")
+}
+
+// CommitSSLCRLFile commit the transaction of a CRL file
+func (h *HaproxyInstace) CommitSSLCRLFile(file string) error {
+	return h.sslFileCommand("commit ssl crl-file", file, "Success!")
+}
+
+// AbortSSLCRLFile abort the transaction of a CRL file
+func (h *HaproxyInstace) AbortSSLCRLFile(file string) error {
+	return h.sslFileCommand("abort ssl crl-file", file, "Transaction aborted")
+}
+
+// DelSSLCRLFile delete an unused CRL file
+func (h *HaproxyInstace) DelSSLCRLFile(file string) error {
+	return h.sslFileCommand("del ssl crl-file", file, "deleted!")
+}
+
+// UpdateSSLCRLFile replaces the contents of a CRL file and commits it, if something goes wrong the transaction is aborted
+func (h *HaproxyInstace) UpdateSSLCRLFile(file, payload string) error {
+	err := h.SetSSLCRLFile(file, payload)
+	if err != nil {
+		return err
+	}
+	err = h.CommitSSLCRLFile(file)
+	if err != nil {
+		h.AbortSSLCRLFile(file)
+		return err
+	}
+	return nil
+}
+
+// ShowSSLCRLFiles list all loaded CRL files and the current transaction
+func (h *HaproxyInstace) ShowSSLCRLFiles() (SSLFileListT, error) {
+	return h.showSSLFiles("show ssl crl-file")
+}
+
+// SSLRevokedT is a revoked certificate from a CRL
+type SSLRevokedT struct {
+	Serial         string    `json:"serial"`
+	RevocationDate time.Time `json:"revocationDate"`
+}
+
+// SSLCRLT is a single certificate revocation list
+type SSLCRLT struct {
+	Version            string        `json:"version"`
+	SignatureAlgorithm string        `json:"signatureAlgorithm"`
+	Issuer             string        `json:"issuer"`
+	LastUpdate         time.Time     `json:"lastUpdate"`
+	NextUpdate         time.Time     `json:"nextUpdate"`
+	Revoked            []SSLRevokedT `json:"revoked"`
+}
+
+// SSLCRLFileT are the details of a CRL file
+type SSLCRLFileT struct {
+	Filename string    `json:"filename"`
+	Status   string    `json:"status"`
+	CRLs     []SSLCRLT `json:"crls"`
+}
+
+// ShowSSLCRLFile report the revocation lists of a CRL file, use *<file> to show the uncommitted transaction
+func (h *HaproxyInstace) ShowSSLCRLFile(file string) (SSLCRLFileT, error) {
+	toReturn := SSLCRLFileT{CRLs: []SSLCRLT{}}
+	if file == "" {
+		return toReturn, errors.New("file can't be empty")
+	}
+	out, err := h.q("show ssl crl-file " + file)
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "Filename:") {
+		return toReturn, errors.New(out)
+	}
+
+	// The output looks like:
+	// Certificate Revocation List #1:
+	// Version 1
+	// Signature Algorithm: sha256WithRSAEncryption
+	// Issuer: /C=FR/O=HAProxy Technologies/CN=Intermediate CA2
+	// Last Update: Apr 23 14:45:39 2021 GMT
+	// Next Update: Sep  8 14:45:39 2048 GMT
+	// Revoked Certificates:
+	//     Serial Number: 1008
+	//         Revocation Date: Apr 23 14:45:36 2021 GMT
+	var crl *SSLCRLT
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Certificate Revocation List #") {
+			toReturn.CRLs = append(toReturn.CRLs, SSLCRLT{Revoked: []SSLRevokedT{}})
+			crl = &toReturn.CRLs[len(toReturn.CRLs)-1]
+			continue
+		}
+		if strings.HasPrefix(line, "Version") && crl != nil {
+			crl.Version = strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(line, "Version"), ":"))
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		if crl == nil {
+			switch parts[0] {
+			case "Filename":
+				toReturn.Filename = value
+			case "Status":
+				toReturn.Status = value
+			}
+			continue
+		}
+		switch parts[0] {
+		case "Signature Algorithm":
+			crl.SignatureAlgorithm = value
+		case "Issuer":
+			crl.Issuer = value
+		case "Last Update":
+			crl.LastUpdate = parseSSLDate(value)
+		case "Next Update":
+			crl.NextUpdate = parseSSLDate(value)
+		case "Serial Number":
+			crl.Revoked = append(crl.Revoked, SSLRevokedT{Serial: value})
+		case "Revocation Date":
+			if len(crl.Revoked) > 0 {
+				crl.Revoked[len(crl.Revoked)-1].RevocationDate = parseSSLDate(value)
+			}
+		}
+	}
+
+	return toReturn, nil
+}
@@ -0,0 +1,113 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShowSSLFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		expected  SSLFileListT
+		expectErr bool
+	}{
+		{
+			name: "with transaction",
+			out: "# transaction\n" +
+				"*cafile.crt - 2 certificate(s)\n" +
+				"# filename\n" +
+				"cafile.crt - 1 certificate(s)\n" +
+				"/etc/haproxy/ca/internal.crt - 3 certificate(s)\n",
+			expected: SSLFileListT{
+				Transaction: "cafile.crt",
+				Files: []SSLFileT{
+					{Name: "cafile.crt", Entries: 1},
+					{Name: "/etc/haproxy/ca/internal.crt", Entries: 3},
+				},
+			},
+		},
+		{
+			name: "crl files",
+			out: "# filename\n" +
+				"crlfile.pem\n",
+			expected: SSLFileListT{
+				Files: []SSLFileT{{Name: "crlfile.pem"}},
+			},
+		},
+		{
+			name:      "unknown command",
+			out:       "Unknown command: 'show ssl ca-file', but maybe one of the following ones is a better match:\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := cannedInstance(t, map[string]string{"show ssl ca-file": test.out})
+			list, err := h.ShowSSLCAFiles()
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(list, test.expected) {
+				t.Errorf("got %+v, expected %+v", list, test.expected)
+			}
+		})
+	}
+}
+
+func TestShowSSLCAFile(t *testing.T) {
+	h := cannedInstance(t, map[string]string{
+		"show ssl ca-file cafile.crt": "Filename: /etc/haproxy/ca/cafile.crt\n" +
+			"Status: Used\n" +
+			"\n" +
+			"Certificate #1:\n" +
+			"  Serial: 11A4D2200DC84376E7D233CAFF39DF44BF8D1211\n" +
+			"  notBefore: Apr  1 07:40:53 2021 GMT\n" +
+			"  notAfter: Aug 17 07:40:53 2048 GMT\n" +
+			"  Subject Alternative Name:\n" +
+			"  Algorithm: RSA4096\n" +
+			"  SHA1 FingerPrint: A111EF0FEFCDE11D47FE3F33ADCA8435EBEA4864\n" +
+			"  Subject: /C=FR/ST=Some-State/O=HAProxy Technologies/CN=HAProxy Technologies CA\n" +
+			"  Issuer: /C=FR/ST=Some-State/O=HAProxy Technologies/CN=HAProxy Technologies CA\n" +
+			"\n" +
+			"Certificate #2:\n" +
+			"  Serial: 0FD078DD48F1A2BD4D0F2BA96B6038FE\n" +
+			"  notBefore: Apr 14 00:00:00 2021 GMT\n" +
+			"  notAfter: Apr 13 23:59:59 2031 GMT\n" +
+			"  Subject Alternative Name:\n" +
+			"  Algorithm: RSA2048\n" +
+			"  SHA1 FingerPrint: 1B5AC2C3F3CE5D1D9C2A3D38FD64D8B4AB4C5D8E\n" +
+			"  Subject: /C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1\n" +
+			"  Issuer: /C=US/O=DigiCert Inc/OU=www.digicert.com/CN=DigiCert Global Root CA\n",
+	})
+	caFile, err := h.ShowSSLCAFile("cafile.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caFile.Filename != "/etc/haproxy/ca/cafile.crt" || caFile.Status != "Used" {
+		t.Errorf("got filename %q and status %q", caFile.Filename, caFile.Status)
+	}
+	if len(caFile.Certificates) != 2 {
+		t.Fatalf("got %v certificates, expected 2", len(caFile.Certificates))
+	}
+
+	root := caFile.Certificates[0]
+	if root.Serial != "11A4D2200DC84376E7D233CAFF39DF44BF8D1211" || root.Algorithm != "RSA4096" || len(root.SANs) != 0 || !root.ChainComplete {
+		t.Errorf("unexpected first certificate %+v", root)
+	}
+	if root.NotAfter.Year() != 2048 {
+		t.Errorf("got notAfter %v, expected a date in 2048", root.NotAfter)
+	}
+
+	intermediate := caFile.Certificates[1]
+	if intermediate.Subject != "/C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1" || intermediate.ChainComplete {
+		t.Errorf("unexpected second certificate %+v", intermediate)
+	}
+}
@@ -0,0 +1,321 @@
+package haproxysocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShiftGroupT is a group of servers inside one backend
+type ShiftGroupT struct {
+	Backend string   `json:"backend"`
+	Servers []string `json:"servers"`
+}
+
+// ShiftThresholdsT are the max allowed error values per step
+// A value of 0 means the check is disabled
+type ShiftThresholdsT struct {
+	Max5xx       uint64  `json:"max5xx"`       // Max new hrsp_5xx responses
+	MaxEresp     uint64  `json:"maxEresp"`     // Max new response errors
+	MaxEcon      uint64  `json:"maxEcon"`      // Max new connection errors
+	MaxErrorRate float64 `json:"maxErrorRate"` // Max (hrsp_5xx + eresp + econ) / stot, between 0 and 1
+}
+
+// ShiftMetricsT are the error counters gathered from the target during a step
+type ShiftMetricsT struct {
+	Hrsp5xx  uint64 `json:"hrsp5xx"`
+	Eresp    uint64 `json:"eresp"`
+	Econ     uint64 `json:"econ"`
+	Sessions uint64 `json:"sessions"` // stot
+}
+
+// ErrorRate returns the amount of errors divided by the amount of sessions
+func (m ShiftMetricsT) ErrorRate() float64 {
+	if m.Sessions == 0 {
+		return 0
+	}
+	return float64(m.Hrsp5xx+m.Eresp+m.Econ) / float64(m.Sessions)
+}
+
+func (m ShiftMetricsT) sub(old ShiftMetricsT) ShiftMetricsT {
+	delta := func(n, o uint64) uint64 {
+		// Counters went down, most likely cleared or haproxy got reloaded
+		if n < o {
+			return n
+		}
+		return n - o
+	}
+	return ShiftMetricsT{
+		Hrsp5xx:  delta(m.Hrsp5xx, old.Hrsp5xx),
+		Eresp:    delta(m.Eresp, old.Eresp),
+		Econ:     delta(m.Econ, old.Econ),
+		Sessions: delta(m.Sessions, old.Sessions),
+	}
+}
+
+// ShiftStepT is the result of one step
+type ShiftStepT struct {
+	Percent uint          `json:"percent"` // The percentage of traffic send to the target
+	Metrics ShiftMetricsT `json:"metrics"` // The error counters of the target during this step
+	Err     error         `json:"-"`
+}
+
+// ShiftReportT is the result of a traffic shift
+type ShiftReportT struct {
+	Steps    []ShiftStepT `json:"steps"`
+	Reverted bool         `json:"reverted"`
+}
+
+// TrafficShiftT moves traffic in steps from one server group (or backend) to another
+// Create one using ShiftServers or ShiftMap and start it with Run
+type TrafficShiftT struct {
+	h *HaproxyInstace
+
+	From ShiftGroupT
+	To   ShiftGroupT
+
+	// When Map is set the percentage send to the To backend is written to this map entry
+	// instead of changing the server weights, the From and To groups are then only used to read the metrics
+	Map string
+	Key string
+
+	Steps      []uint        // The percentages of traffic send to the target, defaults to 10, 25, 50, 100
+	Interval   time.Duration // The time to wait after a step before checking the metrics, defaults to 1 minute
+	MaxWeight  uint          // The weight used for 100%, defaults to 100 (max 256)
+	Thresholds ShiftThresholdsT
+	OnStep     func(step ShiftStepT) // Optional, called after every successful step
+}
+
+// ShiftServers creates a traffic shift that moves weight from the servers in from to the servers in to
+func (h *HaproxyInstace) ShiftServers(from, to ShiftGroupT) *TrafficShiftT {
+	return &TrafficShiftT{
+		h:    h,
+		From: from,
+		To:   to,
+	}
+}
+
+// ShiftMap creates a traffic shift that writes the percentage of traffic for toBackend into a map entry
+// The haproxy config is expected to use this value for picking a backend, for example:
+// use_backend %[rand(100),map_...] or an acl comparing rand(100) with the map value
+func (h *HaproxyInstace) ShiftMap(mapID, key, fromBackend, toBackend string) *TrafficShiftT {
+	return &TrafficShiftT{
+		h:    h,
+		From: ShiftGroupT{Backend: fromBackend},
+		To:   ShiftGroupT{Backend: toBackend},
+		Map:  mapID,
+		Key:  key,
+	}
+}
+
+// Run executes all steps, if a step exceeds one of the thresholds or fails
+// the original weights (or map value) are restored and an error is returned
+func (t *TrafficShiftT) Run(ctx context.Context) (ShiftReportT, error) {
+	report := ShiftReportT{Steps: []ShiftStepT{}}
+
+	steps := t.Steps
+	if len(steps) == 0 {
+		steps = []uint{10, 25, 50, 100}
+	}
+	for _, step := range steps {
+		if step > 100 {
+			return report, errors.New("steps can't be more than 100")
+		}
+	}
+	interval := t.Interval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	if t.MaxWeight > 256 {
+		return report, errors.New("MaxWeight can't be more than 256")
+	}
+	if t.To.Backend == "" || t.From.Backend == "" {
+		return report, errors.New("From and To need a backend")
+	}
+	if t.Map == "" && (len(t.To.Servers) == 0 || len(t.From.Servers) == 0) {
+		return report, errors.New("From and To need at least 1 server")
+	}
+
+	revert, err := t.saveState()
+	if err != nil {
+		return report, err
+	}
+	fail := func(err error) (ShiftReportT, error) {
+		revertErr := revert()
+		if revertErr != nil {
+			return report, fmt.Errorf("%v, reverting also failed: %v", err, revertErr)
+		}
+		report.Reverted = true
+		return report, err
+	}
+
+	before, err := t.metrics()
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, percent := range steps {
+		err = t.apply(percent)
+		if err != nil {
+			return fail(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fail(ctx.Err())
+		case <-time.After(interval):
+		}
+
+		after, err := t.metrics()
+		if err != nil {
+			return fail(err)
+		}
+		step := ShiftStepT{
+			Percent: percent,
+			Metrics: after.sub(before),
+		}
+		before = after
+
+		step.Err = t.check(step.Metrics)
+		report.Steps = append(report.Steps, step)
+		if step.Err != nil {
+			return fail(fmt.Errorf("step %v%%: %v", percent, step.Err))
+		}
+		if t.OnStep != nil {
+			t.OnStep(step)
+		}
+	}
+
+	return report, nil
+}
+
+// check validates the metrics of a step against the thresholds
+func (t *TrafficShiftT) check(m ShiftMetricsT) error {
+	th := t.Thresholds
+	if th.Max5xx > 0 && m.Hrsp5xx > th.Max5xx {
+		return fmt.Errorf("hrsp_5xx %v is more than %v", m.Hrsp5xx, th.Max5xx)
+	}
+	if th.MaxEresp > 0 && m.Eresp > th.MaxEresp {
+		return fmt.Errorf("eresp %v is more than %v", m.Eresp, th.MaxEresp)
+	}
+	if th.MaxEcon > 0 && m.Econ > th.MaxEcon {
+		return fmt.Errorf("econ %v is more than %v", m.Econ, th.MaxEcon)
+	}
+	if th.MaxErrorRate > 0 && m.ErrorRate() > th.MaxErrorRate {
+		return fmt.Errorf("error rate %.4f is more than %.4f", m.ErrorRate(), th.MaxErrorRate)
+	}
+	return nil
+}
+
+// apply sends percent of the traffic to the target
+func (t *TrafficShiftT) apply(percent uint) error {
+	if t.Map != "" {
+		return t.h.SetMap(t.Map, t.Key, strconv.Itoa(int(percent)))
+	}
+
+	maxWeight := t.MaxWeight
+	if maxWeight == 0 {
+		maxWeight = 100
+	}
+	toWeight := strconv.Itoa(int(maxWeight * percent / 100))
+	fromWeight := strconv.Itoa(int(maxWeight * (100 - percent) / 100))
+
+	for _, server := range t.To.Servers {
+		err := t.h.SetWeight(t.To.Backend, server, toWeight)
+		if err != nil {
+			return err
+		}
+	}
+	for _, server := range t.From.Servers {
+		err := t.h.SetWeight(t.From.Backend, server, fromWeight)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveState records the current weights (or map value) and returns a function that restores them
+func (t *TrafficShiftT) saveState() (func() error, error) {
+	if t.Map != "" {
+		entries, err := t.h.ShowMap(t.Map)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Key != t.Key {
+				continue
+			}
+			value := entry.Value
+			return func() error {
+				return t.h.SetMap(t.Map, t.Key, value)
+			}, nil
+		}
+		return nil, errors.New("key " + t.Key + " not found in map " + t.Map)
+	}
+
+	type savedWeight struct {
+		backend string
+		server  string
+		weight  string
+	}
+	saved := []savedWeight{}
+	for _, group := range []ShiftGroupT{t.From, t.To} {
+		for _, server := range group.Servers {
+			weight, err := t.h.GetWeight(group.Backend, server)
+			if err != nil {
+				return nil, err
+			}
+			// The output looks like: "1 (initial 1)"
+			weight = strings.SplitN(weight, " ", 2)[0]
+			if _, err := strconv.Atoi(weight); err != nil {
+				return nil, errors.New("unable to get weight of " + group.Backend + "/" + server + ": " + weight)
+			}
+			saved = append(saved, savedWeight{group.Backend, server, weight})
+		}
+	}
+	return func() error {
+		errs := []string{}
+		for _, s := range saved {
+			err := t.h.SetWeight(s.backend, s.server, s.weight)
+			if err != nil {
+				errs = append(errs, s.backend+"/"+s.server+": "+err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			return errors.New(strings.Join(errs, ", "))
+		}
+		return nil
+	}, nil
+}
+
+// metrics sums the error counters of the target
+func (t *TrafficShiftT) metrics() (ShiftMetricsT, error) {
+	toReturn := ShiftMetricsT{}
+	stats, err := t.h.ShowStat()
+	if err != nil {
+		return toReturn, err
+	}
+
+	for _, row := range stats {
+		if row["pxname"] != t.To.Backend {
+			continue
+		}
+		if t.Map != "" {
+			if row["svname"] != "BACKEND" {
+				continue
+			}
+		} else if !inList(row["svname"], t.To.Servers) {
+			continue
+		}
+		toReturn.Hrsp5xx += statUint(row, "hrsp_5xx")
+		toReturn.Eresp += statUint(row, "eresp")
+		toReturn.Econ += statUint(row, "econ")
+		toReturn.Sessions += statUint(row, "stot")
+	}
+
+	return toReturn, nil
+}
@@ -0,0 +1,184 @@
+package haproxysocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The kinds of certificate events
+const (
+	CertEventExpiring = "expiring" // The certificate expires within the threshold
+	CertEventExpired  = "expired"  // The certificate is expired
+	CertEventPending  = "pending"  // The certificate has an uncommitted transaction
+	CertEventMismatch = "mismatch" // A crt-list entry references a certificate that isn't loaded
+	CertEventError    = "error"    // Reading the certificate data failed
+)
+
+// CertEventT is reported by the CertMonitorT
+type CertEventT struct {
+	Kind     string    `json:"kind"`
+	File     string    `json:"file"`
+	CrtList  string    `json:"crtList,omitempty"` // Only set for mismatch events
+	NotAfter time.Time `json:"notAfter,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// CertReportT is the result of a single check
+type CertReportT struct {
+	Time   time.Time           `json:"time"`
+	Certs  map[string]SSLCertT `json:"certs"` // All loaded certificates by file name
+	Events []CertEventT        `json:"events"`
+}
+
+// CertMonitorT checks the loaded certificates, create one using CertMonitor
+type CertMonitorT struct {
+	h *HaproxyInstace
+	m sync.Mutex
+
+	Threshold time.Duration          // Report certificates that expire within this duration, defaults to 30 days
+	Interval  time.Duration          // The time between checks when using Run, defaults to 1 hour
+	OnEvent   func(event CertEventT) // Optional, called once for every new problem, it's called again when the problem disappeared and came back
+
+	last     CertReportT
+	reported map[string]bool
+}
+
+// CertMonitor creates a certificate monitor
+func (h *HaproxyInstace) CertMonitor() *CertMonitorT {
+	return &CertMonitorT{
+		h:        h,
+		reported: map[string]bool{},
+	}
+}
+
+// CheckOnce checks all loaded certificates and crt-lists
+func (c *CertMonitorT) CheckOnce() (CertReportT, error) {
+	report := CertReportT{
+		Time:   time.Now(),
+		Certs:  map[string]SSLCertT{},
+		Events: []CertEventT{},
+	}
+	threshold := c.Threshold
+	if threshold == 0 {
+		threshold = 30 * 24 * time.Hour
+	}
+
+	list, err := c.h.ShowSSLCerts()
+	if err != nil {
+		return report, err
+	}
+	if list.Transaction != "" {
+		report.Events = append(report.Events, CertEventT{
+			Kind:    CertEventPending,
+			File:    list.Transaction,
+			Message: "uncommitted transaction for " + list.Transaction,
+		})
+	}
+
+	for _, file := range list.Files {
+		cert, err := c.h.ShowSSLCert(file)
+		if err != nil {
+			report.Events = append(report.Events, CertEventT{
+				Kind:    CertEventError,
+				File:    file,
+				Message: err.Error(),
+			})
+			continue
+		}
+		report.Certs[file] = cert
+		if cert.NotAfter.IsZero() {
+			// Empty certificate store
+			continue
+		}
+
+		left := cert.NotAfter.Sub(report.Time)
+		if left <= 0 {
+			report.Events = append(report.Events, CertEventT{
+				Kind:     CertEventExpired,
+				File:     file,
+				NotAfter: cert.NotAfter,
+				Message:  fmt.Sprintf("%v expired at %v", file, cert.NotAfter),
+			})
+		} else if left < threshold {
+			report.Events = append(report.Events, CertEventT{
+				Kind:     CertEventExpiring,
+				File:     file,
+				NotAfter: cert.NotAfter,
+				Message:  fmt.Sprintf("%v expires in %v", file, left.Round(time.Minute)),
+			})
+		}
+	}
+
+	crtLists, err := c.h.ShowSSLCrtLists()
+	if err == nil {
+		for _, crtList := range crtLists {
+			entries, err := c.h.ShowSSLCrtList(crtList, false)
+			if err != nil {
+				report.Events = append(report.Events, CertEventT{
+					Kind:    CertEventError,
+					CrtList: crtList,
+					Message: err.Error(),
+				})
+				continue
+			}
+			for _, entry := range entries {
+				if _, ok := report.Certs[entry.Cert]; ok {
+					continue
+				}
+				report.Events = append(report.Events, CertEventT{
+					Kind:    CertEventMismatch,
+					File:    entry.Cert,
+					CrtList: crtList,
+					Message: "crt-list " + crtList + " references " + entry.Cert + " which is not loaded",
+				})
+			}
+		}
+	}
+
+	c.m.Lock()
+	c.last = report
+	seen := map[string]bool{}
+	newEvents := []CertEventT{}
+	for _, event := range report.Events {
+		key := event.Kind + " " + event.CrtList + " " + event.File
+		seen[key] = true
+		if !c.reported[key] {
+			newEvents = append(newEvents, event)
+		}
+	}
+	c.reported = seen
+	c.m.Unlock()
+
+	if c.OnEvent != nil {
+		for _, event := range newEvents {
+			c.OnEvent(event)
+		}
+	}
+
+	return report, nil
+}
+
+// Run calls CheckOnce every Interval until ctx is done
+func (c *CertMonitorT) Run(ctx context.Context) {
+	interval := c.Interval
+	if interval == 0 {
+		interval = time.Hour
+	}
+	for {
+		c.CheckOnce()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Last returns the report of the last check
+func (c *CertMonitorT) Last() CertReportT {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.last
+}
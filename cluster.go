@@ -0,0 +1,358 @@
+package haproxysocket
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClusterT wraps multiple haproxy instances so commands can be executed on all of them at once
+type ClusterT struct {
+	Nodes []*HaproxyInstace
+	// Names are the optional unique names of the nodes used in the results, by default the address is used
+	// followed by the master cli route for workers and #<index> if multiple nodes have the same address
+	Names []string
+}
+
+// NewCluster creates a cluster of haproxy instances
+func NewCluster(nodes ...*HaproxyInstace) *ClusterT {
+	return &ClusterT{
+		Nodes: nodes,
+	}
+}
+
+// ClusterPolicy decides what happens when a command fails on some of the nodes
+type ClusterPolicy int
+
+const (
+	// BestEffort keeps the changes on the nodes where the command succeeded
+	BestEffort ClusterPolicy = iota
+	// AllMustSucceed undoes the changes on the nodes where the command succeeded if one of the nodes failed
+	AllMustSucceed
+)
+
+// NodeResultT is the result of a command on a single node
+type NodeResultT struct {
+	Node        string `json:"node"` // The name of the node, see ClusterT.Names
+	Err         error  `json:"-"`
+	Error       string `json:"error,omitempty"`
+	Skipped     bool   `json:"skipped"` // Prepare failed on this node so the command wasn't executed
+	RolledBack  bool   `json:"rolledBack"`
+	RollbackErr string `json:"rollbackError,omitempty"`
+}
+
+// ClusterResultT is the result of a command on all nodes
+type ClusterResultT struct {
+	Nodes []NodeResultT `json:"nodes"`
+}
+
+// Failed returns the results of the nodes where the command failed
+func (r ClusterResultT) Failed() []NodeResultT {
+	toReturn := []NodeResultT{}
+	for _, node := range r.Nodes {
+		if node.Err != nil {
+			toReturn = append(toReturn, node)
+		}
+	}
+	return toReturn
+}
+
+// ClusterOpT is a command that can be executed on a cluster
+type ClusterOpT struct {
+	// Prepare is optional and is called before Do, it can record the current state that's needed by Undo
+	// The returned value is passed to Undo
+	Prepare func(h *HaproxyInstace) (interface{}, error)
+	Do      func(h *HaproxyInstace) error
+	// Undo is optional and is used by the AllMustSucceed policy to roll back nodes where Do succeeded
+	Undo func(h *HaproxyInstace, prepared interface{}) error
+}
+
+// Each executes fn on all nodes in parallel and waits for all of them to finish
+func (c *ClusterT) Each(fn func(h *HaproxyInstace) error) ClusterResultT {
+	return c.each(func(i int, h *HaproxyInstace) error {
+		return fn(h)
+	})
+}
+
+// each is the same as Each but also passes the index of the node to fn
+func (c *ClusterT) each(fn func(i int, h *HaproxyInstace) error) ClusterResultT {
+	toReturn := ClusterResultT{Nodes: make([]NodeResultT, len(c.Nodes))}
+	var wg sync.WaitGroup
+	for i, node := range c.Nodes {
+		wg.Add(1)
+		go func(i int, node *HaproxyInstace) {
+			defer wg.Done()
+			err := fn(i, node)
+			toReturn.Nodes[i] = nodeResult(c.NodeName(i), err)
+		}(i, node)
+	}
+	wg.Wait()
+	return toReturn
+}
+
+// NodeName returns the name of the node at index i
+func (c *ClusterT) NodeName(i int) string {
+	if i < len(c.Names) && c.Names[i] != "" {
+		return c.Names[i]
+	}
+	name := func(h *HaproxyInstace) string {
+		if h.prefix != "" {
+			return h.Address + " " + h.prefix
+		}
+		return h.Address
+	}
+	toReturn := name(c.Nodes[i])
+	for j, node := range c.Nodes {
+		if j != i && name(node) == toReturn {
+			return toReturn + " #" + strconv.Itoa(i)
+		}
+	}
+	return toReturn
+}
+
+func nodeResult(node string, err error) NodeResultT {
+	toReturn := NodeResultT{Node: node, Err: err}
+	if err != nil {
+		toReturn.Error = err.Error()
+	}
+	return toReturn
+}
+
+// Apply executes op on all nodes in parallel using the policy
+// An error is returned if the command failed on one of the nodes
+// If Prepare fails on a node BestEffort skips that node while AllMustSucceed doesn't change any node
+func (c *ClusterT) Apply(op ClusterOpT, policy ClusterPolicy) (ClusterResultT, error) {
+	prepared := make([]interface{}, len(c.Nodes))
+	prepareErrs := make([]error, len(c.Nodes))
+	if op.Prepare != nil {
+		result := c.each(func(i int, h *HaproxyInstace) error {
+			value, err := op.Prepare(h)
+			prepared[i] = value
+			prepareErrs[i] = err
+			return err
+		})
+		if failed := len(result.Failed()); failed > 0 && policy == AllMustSucceed {
+			return result, errors.New("preparing failed on " + strconv.Itoa(failed) + " nodes, nothing is changed")
+		}
+	}
+
+	result := c.each(func(i int, h *HaproxyInstace) error {
+		if prepareErrs[i] != nil {
+			return errors.New("preparing failed: " + prepareErrs[i].Error())
+		}
+		return op.Do(h)
+	})
+	for i := range result.Nodes {
+		result.Nodes[i].Skipped = prepareErrs[i] != nil
+	}
+	failed := len(result.Failed())
+	if failed == 0 {
+		return result, nil
+	}
+	if policy == BestEffort || op.Undo == nil {
+		return result, errors.New("failed on " + strconv.Itoa(failed) + " nodes")
+	}
+
+	// Roll back the nodes where the command succeeded
+	var wg sync.WaitGroup
+	for i := range result.Nodes {
+		if result.Nodes[i].Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := op.Undo(c.Nodes[i], prepared[i])
+			result.Nodes[i].RolledBack = err == nil
+			if err != nil {
+				result.Nodes[i].RollbackErr = err.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return result, errors.New("failed on " + strconv.Itoa(failed) + " nodes, the other nodes are rolled back")
+}
+
+// ServerState sets the state of a server on all nodes, see ServerT.State
+// With AllMustSucceed the previous state is restored on all nodes if one of them failed
+func (c *ClusterT) ServerState(backend, server, state string, policy ClusterPolicy) (ClusterResultT, error) {
+	return c.Apply(ClusterOpT{
+		Prepare: func(h *HaproxyInstace) (interface{}, error) {
+			servers, err := h.ShowServersState(backend)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range servers {
+				if s.Server == server {
+					return s.AdminState.Forced(), nil
+				}
+			}
+			return nil, errors.New("server " + backend + "/" + server + " not found")
+		},
+		Do: func(h *HaproxyInstace) error {
+			return h.Server(backend, server).State(state)
+		},
+		Undo: func(h *HaproxyInstace, prepared interface{}) error {
+			return h.Server(backend, server).State(prepared.(string))
+		},
+	}, policy)
+}
+
+// SetWeight sets the weight of a server on all nodes, see SetWeight
+// With AllMustSucceed the previous weight is restored on all nodes if one of them failed
+func (c *ClusterT) SetWeight(backend, server, weight string, policy ClusterPolicy) (ClusterResultT, error) {
+	return c.Apply(ClusterOpT{
+		Prepare: func(h *HaproxyInstace) (interface{}, error) {
+			current, err := h.GetWeight(backend, server)
+			if err != nil {
+				return nil, err
+			}
+			// The output looks like: "1 (initial 1)"
+			current = strings.SplitN(current, " ", 2)[0]
+			if _, err := strconv.Atoi(current); err != nil {
+				return nil, errors.New("unable to get weight of " + backend + "/" + server + ": " + current)
+			}
+			return current, nil
+		},
+		Do: func(h *HaproxyInstace) error {
+			return h.SetWeight(backend, server, weight)
+		},
+		Undo: func(h *HaproxyInstace, prepared interface{}) error {
+			return h.SetWeight(backend, server, prepared.(string))
+		},
+	}, policy)
+}
+
+// The "show stat" fields that are not summed when aggregating
+var clusterStatIDFields = map[string]bool{
+	"pxname": true,
+	"svname": true,
+	"pid":    true,
+	"iid":    true,
+	"sid":    true,
+	"type":   true,
+}
+
+// The current gauges of "show stat" that are summed next to the StatCounters when aggregating
+var clusterStatGauges = []string{"scur", "qcur", "rate", "req_rate", "conn_rate"}
+
+// ClusterStatT is the "show stat" output of all nodes
+type ClusterStatT struct {
+	Nodes map[string][]map[string]string `json:"nodes"` // The output of every node by node name
+	// The rows of all nodes combined by pxname and svname, the counters and current gauges are summed
+	// other numeric values like averages, timers and limits are the maximum of all nodes
+	// other values are kept if they are equal on all nodes or else joined with a comma
+	Summed []map[string]string `json:"summed"`
+	Errors map[string]string   `json:"errors"` // The nodes where "show stat" failed
+}
+
+// ShowStat executes "show stat" on all nodes and combines the results
+func (c *ClusterT) ShowStat() (ClusterStatT, error) {
+	toReturn := ClusterStatT{
+		Nodes:  map[string][]map[string]string{},
+		Summed: []map[string]string{},
+		Errors: map[string]string{},
+	}
+
+	var m sync.Mutex
+	c.each(func(i int, h *HaproxyInstace) error {
+		stats, err := h.ShowStat()
+		m.Lock()
+		defer m.Unlock()
+		if err != nil {
+			toReturn.Errors[c.NodeName(i)] = err.Error()
+			return err
+		}
+		toReturn.Nodes[c.NodeName(i)] = stats
+		return nil
+	})
+	if len(toReturn.Nodes) == 0 {
+		return toReturn, errors.New("show stat failed on all nodes")
+	}
+
+	nodes := []string{}
+	for node := range toReturn.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	keys := []string{}
+	combined := map[string][]map[string]string{}
+	for _, node := range nodes {
+		for _, row := range toReturn.Nodes[node] {
+			key := row["pxname"] + "/" + row["svname"]
+			if _, ok := combined[key]; !ok {
+				keys = append(keys, key)
+			}
+			combined[key] = append(combined[key], row)
+		}
+	}
+
+	for _, key := range keys {
+		rows := combined[key]
+		summed := map[string]string{}
+		for field := range rows[0] {
+			if clusterStatIDFields[field] {
+				summed[field] = rows[0][field]
+				continue
+			}
+
+			sum := inList(field, StatCounters) || inList(field, clusterStatGauges)
+			var total uint64
+			numeric := true
+			values := []string{}
+			for _, row := range rows {
+				value := row[field]
+				if !inList(value, values) {
+					values = append(values, value)
+				}
+				if value == "" {
+					continue
+				}
+				i, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					numeric = false
+					continue
+				}
+				if sum {
+					total += i
+				} else if i > total {
+					total = i
+				}
+			}
+			switch {
+			case numeric && len(values) == 1 && values[0] == "":
+				summed[field] = ""
+			case numeric:
+				summed[field] = strconv.FormatUint(total, 10)
+			default:
+				summed[field] = strings.Join(values, ",")
+			}
+		}
+		toReturn.Summed = append(toReturn.Summed, summed)
+	}
+
+	return toReturn, nil
+}
+
+// ShowInfo executes "show info" on all nodes, the result is keyed by node name
+func (c *ClusterT) ShowInfo() (map[string]map[string]string, error) {
+	toReturn := map[string]map[string]string{}
+	var m sync.Mutex
+	result := c.each(func(i int, h *HaproxyInstace) error {
+		info, err := h.ShowInfo()
+		if err != nil {
+			return err
+		}
+		m.Lock()
+		toReturn[c.NodeName(i)] = info
+		m.Unlock()
+		return nil
+	})
+	if failed := len(result.Failed()); failed > 0 {
+		return toReturn, errors.New("show info failed on " + strconv.Itoa(failed) + " nodes")
+	}
+	return toReturn, nil
+}
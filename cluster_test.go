@@ -0,0 +1,178 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusterShowStat(t *testing.T) {
+	cluster := NewCluster(
+		cannedInstance(t, map[string]string{
+			"show stat": "# pxname,svname,qcur,scur,slim,stot,bin,qtime,status,type,\n" +
+				"be_app,app1,1,3,100,120,5000,10,UP,2,\n" +
+				"be_app,BACKEND,1,3,200,120,5000,10,UP,1,\n",
+		}),
+		cannedInstance(t, map[string]string{
+			"show stat": "# pxname,svname,qcur,scur,slim,stot,bin,qtime,status,type,\n" +
+				"be_app,app1,0,4,150,80,7000,25,DOWN,2,\n" +
+				"be_app,BACKEND,0,4,200,80,7000,25,UP,1,\n",
+		}),
+	)
+	cluster.Names = []string{"lb1", "lb2"}
+
+	stats, err := cluster.ShowStat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Nodes["lb1"]) != 2 || len(stats.Nodes["lb2"]) != 2 || len(stats.Errors) != 0 {
+		t.Fatalf("unexpected nodes %v and errors %v", stats.Nodes, stats.Errors)
+	}
+
+	expected := []map[string]string{
+		{
+			"pxname": "be_app", "svname": "app1", "type": "2",
+			"qcur": "1", "scur": "7", "stot": "200", "bin": "12000", // Counters and current gauges are summed
+			"slim": "150", "qtime": "25", // Other numeric values are the maximum
+			"status": "UP,DOWN",
+		},
+		{
+			"pxname": "be_app", "svname": "BACKEND", "type": "1",
+			"qcur": "1", "scur": "7", "stot": "200", "bin": "12000",
+			"slim": "200", "qtime": "25",
+			"status": "UP",
+		},
+	}
+	for _, row := range stats.Summed {
+		// The trailing comma of "show stat" creates an empty column
+		delete(row, "")
+	}
+	if !reflect.DeepEqual(stats.Summed, expected) {
+		t.Errorf("got\n%v\nexpected\n%v", stats.Summed, expected)
+	}
+}
+
+func TestClusterApply(t *testing.T) {
+	serversState := "1\n" + serversStateHeader + "\n" +
+		"3 be_app 1 app1 10.0.0.1 2 0 1 1 1250 6 3 4 6 0 0 0 - 80 - 0 0 - - 0\n"
+
+	tests := []struct {
+		name               string
+		policy             ClusterPolicy
+		nodes              []map[string]string
+		expectErr          bool
+		expectedErrors     []bool
+		expectedSkipped    []bool
+		expectedRolledBack []bool
+	}{
+		{
+			name:   "all succeed",
+			policy: AllMustSucceed,
+			nodes: []map[string]string{
+				{"show servers state be_app": serversState, "set server be_app/app1 state maint": ""},
+				{"show servers state be_app": serversState, "set server be_app/app1 state maint": ""},
+			},
+			expectedErrors:     []bool{false, false},
+			expectedSkipped:    []bool{false, false},
+			expectedRolledBack: []bool{false, false},
+		},
+		{
+			name:   "one node fails and the other is rolled back",
+			policy: AllMustSucceed,
+			nodes: []map[string]string{
+				{
+					"show servers state be_app":          serversState,
+					"set server be_app/app1 state maint": "",
+					"set server be_app/app1 state ready": "",
+				},
+				{"show servers state be_app": serversState, "set server be_app/app1 state maint": "No such server."},
+			},
+			expectErr:          true,
+			expectedErrors:     []bool{false, true},
+			expectedSkipped:    []bool{false, false},
+			expectedRolledBack: []bool{true, false},
+		},
+		{
+			name:   "one node fails with best effort",
+			policy: BestEffort,
+			nodes: []map[string]string{
+				{"show servers state be_app": serversState, "set server be_app/app1 state maint": ""},
+				{"show servers state be_app": serversState, "set server be_app/app1 state maint": "No such server."},
+			},
+			expectErr:          true,
+			expectedErrors:     []bool{false, true},
+			expectedSkipped:    []bool{false, false},
+			expectedRolledBack: []bool{false, false},
+		},
+		{
+			name:   "prepare fails with best effort",
+			policy: BestEffort,
+			nodes: []map[string]string{
+				{"show servers state be_app": serversState, "set server be_app/app1 state maint": ""},
+				{"show servers state be_app": "Can't find backend.\n"},
+			},
+			expectErr:          true,
+			expectedErrors:     []bool{false, true},
+			expectedSkipped:    []bool{false, true},
+			expectedRolledBack: []bool{false, false},
+		},
+		{
+			name:   "prepare fails with all must succeed",
+			policy: AllMustSucceed,
+			nodes: []map[string]string{
+				{"show servers state be_app": serversState},
+				{"show servers state be_app": "Can't find backend.\n"},
+			},
+			expectErr:          true,
+			expectedErrors:     []bool{false, true},
+			expectedSkipped:    []bool{false, false},
+			expectedRolledBack: []bool{false, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cluster := NewCluster()
+			for _, responses := range test.nodes {
+				cluster.Nodes = append(cluster.Nodes, cannedInstance(t, responses))
+			}
+			result, err := cluster.ServerState("be_app", "app1", "maint", test.policy)
+			if test.expectErr && err == nil {
+				t.Error("expected an error")
+			} else if !test.expectErr && err != nil {
+				t.Error(err)
+			}
+
+			errs := []bool{}
+			skipped := []bool{}
+			rolledBack := []bool{}
+			for _, node := range result.Nodes {
+				errs = append(errs, node.Err != nil)
+				skipped = append(skipped, node.Skipped)
+				rolledBack = append(rolledBack, node.RolledBack)
+			}
+			if !reflect.DeepEqual(errs, test.expectedErrors) {
+				t.Errorf("got errors %v, expected %v", errs, test.expectedErrors)
+			}
+			if !reflect.DeepEqual(skipped, test.expectedSkipped) {
+				t.Errorf("got skipped %v, expected %v", skipped, test.expectedSkipped)
+			}
+			if !reflect.DeepEqual(rolledBack, test.expectedRolledBack) {
+				t.Errorf("got rolled back %v, expected %v", rolledBack, test.expectedRolledBack)
+			}
+		})
+	}
+}
+
+func TestClusterNodeName(t *testing.T) {
+	cluster := NewCluster(New("unix", "/run/a.sock"), New("unix", "/run/b.sock"), New("unix", "/run/b.sock"))
+	expected := []string{"/run/a.sock", "/run/b.sock #1", "/run/b.sock #2"}
+	for i, name := range expected {
+		if got := cluster.NodeName(i); got != name {
+			t.Errorf("node %v: got name %q, expected %q", i, got, name)
+		}
+	}
+	cluster.Names = []string{"", "lb2"}
+	if got := cluster.NodeName(1); got != "lb2" {
+		t.Errorf("got name %q, expected lb2", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mjarkk/haproxysocket"
+	"github.com/mjarkk/haproxysocket/exporter"
+)
+
+func main() {
+	network := flag.String("network", "unix", "The network of the haproxy socket, unix or tcp")
+	address := flag.String("address", "/var/run/haproxy.sock", "The address of the haproxy socket")
+	listen := flag.String("listen", ":9101", "The address to serve the metrics on")
+	path := flag.String("path", "/metrics", "The path to serve the metrics on")
+	namespace := flag.String("namespace", "haproxy", "The prefix of all metrics")
+	sources := flag.String("sources", strings.Join(exporter.AllSources, ","), "Comma separated list of sources to collect")
+	include := flag.String("include", "", "Comma separated list of metric name prefixes to export, exports everything if empty")
+	exclude := flag.String("exclude", "", "Comma separated list of metric name prefixes to not export")
+	flag.Parse()
+
+	e := exporter.New(haproxysocket.New(*network, *address))
+	e.Namespace = *namespace
+	e.Sources = splitList(*sources)
+	e.Include = splitList(*include)
+	e.Exclude = splitList(*exclude)
+
+	http.Handle(*path, e)
+	log.Println("Serving metrics on", *listen+*path)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
+
+func splitList(in string) []string {
+	toReturn := []string{}
+	for _, item := range strings.Split(in, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			toReturn = append(toReturn, item)
+		}
+	}
+	return toReturn
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		"":                  {},
+		"stat":              {"stat"},
+		"stat,info":         {"stat", "info"},
+		" stat , info ,, ":  {"stat", "info"},
+		"frontend_,server_": {"frontend_", "server_"},
+	}
+	for in, expected := range tests {
+		if got := splitList(in); !reflect.DeepEqual(got, expected) {
+			t.Errorf("splitList(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// The kinds of arguments, used for shell completion
+const (
+	argBackend  = "backend"
+	argServer   = "server" // Completed using the previous backend argument
+	argFrontend = "frontend"
+	argState    = "state"
+	argHealth   = "health"
+	argTable    = "table"
+	argOther    = ""
+)
+
+// commandT is a haproxyctl subcommand
+type commandT struct {
+	name string // For example "server set-state"
+	args []string
+	help string
+	// kinds are the argument kinds used for completion, the last kind is repeated for variadic commands
+	kinds   []string
+	minArgs int
+	maxArgs int // -1 for no limit
+	run     func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error)
+}
+
+func noResult(err error) (*resultT, error) {
+	return nil, err
+}
+
+func parseUint(in string) (uint, error) {
+	i, err := strconv.ParseUint(in, 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid number " + in)
+	}
+	return uint(i), nil
+}
+
+var commands = []commandT{
+	{
+		name: "info",
+		help: "Show the process information",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			info, err := h.ShowInfo()
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: info, columns: []string{"field", "value"}}
+			for _, key := range sortedKeys(info) {
+				r.rows = append(r.rows, []string{key, info[key]})
+			}
+			return r, nil
+		},
+	},
+	{
+		name:    "stat",
+		args:    []string{"[proxy]"},
+		help:    "Show the counters of all proxies and servers",
+		kinds:   []string{argBackend},
+		maxArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			stats, err := h.ShowStat()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) == 1 {
+				filtered := []map[string]string{}
+				for _, row := range stats {
+					if row["pxname"] == args[0] {
+						filtered = append(filtered, row)
+					}
+				}
+				stats = filtered
+			}
+			return mapsResult(stats, "pxname", "svname", "status", "weight", "scur", "smax", "stot", "bin", "bout", "hrsp_5xx", "check_status"), nil
+		},
+	},
+	{
+		name: "backends",
+		help: "List the backends",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			backends, err := h.ShowBackend()
+			if err != nil {
+				return nil, err
+			}
+			return mapsResult(backends), nil
+		},
+	},
+	{
+		name:    "servers",
+		args:    []string{"[backend]"},
+		help:    "Show the state of the servers",
+		kinds:   []string{argBackend},
+		maxArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			servers, err := h.ShowServersState(args...)
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: servers, columns: []string{"backend", "server", "addr", "port", "state", "admin", "weight"}}
+			for _, s := range servers {
+				r.rows = append(r.rows, []string{s.Backend, s.Server, s.Addr, strconv.Itoa(s.Port), s.OpState.String(), s.AdminState.Forced(), strconv.Itoa(s.UWeight)})
+			}
+			return r, nil
+		},
+	},
+	{
+		name:    "server set-state",
+		args:    []string{"<backend>", "<server>", "<ready|drain|maint>"},
+		help:    "Set the admin state of a server",
+		kinds:   []string{argBackend, argServer, argState},
+		minArgs: 3,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.Server(args[0], args[1]).State(args[2]))
+		},
+	},
+	{
+		name:    "server set-weight",
+		args:    []string{"<backend>", "<server>", "<weight>"},
+		help:    "Set the weight of a server, can also be a percentage like 50%",
+		kinds:   []string{argBackend, argServer, argOther},
+		minArgs: 3,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.SetWeight(args[0], args[1], args[2]))
+		},
+	},
+	{
+		name:    "server get-weight",
+		args:    []string{"<backend>", "<server>"},
+		help:    "Show the current and initial weight of a server",
+		kinds:   []string{argBackend, argServer},
+		minArgs: 2,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			weight, err := h.GetWeight(args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			return &resultT{value: map[string]string{"weight": weight}, columns: []string{"weight"}, rows: [][]string{{weight}}}, nil
+		},
+	},
+	{
+		name:    "server set-addr",
+		args:    []string{"<backend>", "<server>", "<addr>", "[port]"},
+		help:    "Set the address and optionally the port of a server",
+		kinds:   []string{argBackend, argServer, argOther, argOther},
+		minArgs: 3,
+		maxArgs: 4,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.Server(args[0], args[1]).Addr(args[2], args[3:]...))
+		},
+	},
+	{
+		name:    "server set-health",
+		args:    []string{"<backend>", "<server>", "<up|stopping|down>"},
+		help:    "Force the health status of a server",
+		kinds:   []string{argBackend, argServer, argHealth},
+		minArgs: 3,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.Server(args[0], args[1]).Health(args[2]))
+		},
+	},
+	{
+		name:    "server set-maxconn",
+		args:    []string{"<backend>", "<server>", "<maxconn>"},
+		help:    "Set the maxconn of a server",
+		kinds:   []string{argBackend, argServer, argOther},
+		minArgs: 3,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			maxConn, err := parseUint(args[2])
+			if err != nil {
+				return nil, err
+			}
+			return noResult(h.SetMaxconnServer(args[0], args[1], maxConn))
+		},
+	},
+	{
+		name:    "server shutdown-sessions",
+		args:    []string{"<backend>", "<server>"},
+		help:    "Shut down all sessions of a server",
+		kinds:   []string{argBackend, argServer},
+		minArgs: 2,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.ShutdownSessionsServer(args[0], args[1]))
+		},
+	},
+	{
+		name:    "frontend enable",
+		args:    []string{"<frontend>"},
+		help:    "Enable a frontend",
+		kinds:   []string{argFrontend},
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.EnableFrontend(args[0]))
+		},
+	},
+	{
+		name:    "frontend disable",
+		args:    []string{"<frontend>"},
+		help:    "Disable a frontend",
+		kinds:   []string{argFrontend},
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.DisableFrontend(args[0]))
+		},
+	},
+	{
+		name:    "frontend shutdown",
+		args:    []string{"<frontend>"},
+		help:    "Stop a frontend, it can't be enabled again without a reload",
+		kinds:   []string{argFrontend},
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.ShutdownFrontend(args[0]))
+		},
+	},
+	{
+		name:    "frontend set-maxconn",
+		args:    []string{"<frontend>", "<maxconn>"},
+		help:    "Set the maxconn of a frontend",
+		kinds:   []string{argFrontend, argOther},
+		minArgs: 2,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			maxConn, err := parseUint(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return noResult(h.SetMaxconnFrontend(args[0], maxConn))
+		},
+	},
+	{
+		name: "sess list",
+		help: "List the sessions",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			sessions, err := h.ShowSess()
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: sessions, columns: []string{"id", "source", "frontend", "backend", "server", "age", "cpu", "calls"}}
+			for _, s := range sessions {
+				r.rows = append(r.rows, []string{s.ID, s.Source, s.Frontend, s.Backend, s.Server, s.Age.Round(time.Second).String(), s.CPU.String(), strconv.FormatUint(s.Calls, 10)})
+			}
+			return r, nil
+		},
+	},
+	{
+		name:    "sess show",
+		args:    []string{"<id>"},
+		help:    "Show all details of a session",
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			details, err := h.ShowSessDetail(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return &resultT{value: details}, nil
+		},
+	},
+	{
+		name:    "sess kill",
+		args:    []string{"<id>..."},
+		help:    "Shut down sessions",
+		minArgs: 1,
+		maxArgs: -1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			for _, id := range args {
+				err := h.ShutdownSession(id)
+				if err != nil {
+					return nil, errors.New(id + ": " + err.Error())
+				}
+			}
+			return nil, nil
+		},
+	},
+	{
+		name:    "map show",
+		args:    []string{"<map>"},
+		help:    "Show the entries of a map, map can be the file name or #<id>",
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			entries, err := h.ShowMap(args[0])
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: entries, columns: []string{"id", "key", "value"}}
+			for _, entry := range entries {
+				r.rows = append(r.rows, []string{entry.ID, entry.Key, entry.Value})
+			}
+			return r, nil
+		},
+	},
+	{
+		name:    "map add",
+		args:    []string{"<map>", "<key>", "<value>"},
+		help:    "Add an entry to a map",
+		minArgs: 3,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.AddMap(args[0], args[1], args[2]))
+		},
+	},
+	{
+		name:    "map set",
+		args:    []string{"<map>", "<key>", "<value>"},
+		help:    "Change an entry of a map",
+		minArgs: 3,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.SetMap(args[0], args[1], args[2]))
+		},
+	},
+	{
+		name:    "map del",
+		args:    []string{"<map>", "<key>"},
+		help:    "Delete an entry from a map",
+		minArgs: 2,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.DelMap(args[0], args[1]))
+		},
+	},
+	{
+		name:    "map clear",
+		args:    []string{"<map>"},
+		help:    "Delete all entries from a map",
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			return noResult(h.ClearMap(args[0]))
+		},
+	},
+	{
+		name:    "acl show",
+		args:    []string{"<acl>"},
+		help:    "Show the entries of an acl, acl can be the file name or #<id>",
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			entries, err := h.ShowACL(args[0])
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: entries, columns: []string{"id", "pattern"}}
+			for _, entry := range entries {
+				r.rows = append(r.rows, []string{entry.ID, entry.Pattern})
+			}
+			return r, nil
+		},
+	},
+	{
+		name: "table list",
+		help: "List the stick tables",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			tables, err := h.ShowTables()
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: tables, columns: []string{"name", "type", "size", "used"}}
+			for _, table := range tables {
+				r.rows = append(r.rows, []string{table.Name, table.Type, strconv.FormatUint(table.Size, 10), strconv.FormatUint(table.Used, 10)})
+			}
+			return r, nil
+		},
+	},
+	{
+		name:    "table show",
+		args:    []string{"<table>"},
+		help:    "Show the entries of a stick table",
+		kinds:   []string{argTable},
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			_, entries, err := h.ShowTable(args[0])
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: entries, columns: []string{"key", "use", "exp", "data"}}
+			for _, entry := range entries {
+				data := []string{}
+				for _, key := range sortedKeys(entry.Data) {
+					data = append(data, key+"="+entry.Data[key])
+				}
+				r.rows = append(r.rows, []string{entry.Key, strconv.FormatUint(entry.Use, 10), entry.Exp.String(), strings.Join(data, " ")})
+			}
+			return r, nil
+		},
+	},
+	{
+		name: "pools",
+		help: "Show the memory pools",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			pools, err := h.ShowPools()
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: pools, columns: []string{"name", "size", "used", "failures", "users"}}
+			for _, pool := range pools {
+				r.rows = append(r.rows, []string{pool.Name, pool.Size, strconv.Itoa(int(pool.Used)), strconv.Itoa(int(pool.Failures)), strconv.Itoa(int(pool.Users))})
+			}
+			return r, nil
+		},
+	},
+	{
+		name: "resolvers",
+		help: "Show the resolvers counters",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			resolvers, err := h.ShowStatResolvers()
+			if err != nil {
+				return nil, err
+			}
+			return mapsResult(resolvers), nil
+		},
+	},
+	{
+		name:    "env",
+		args:    []string{"[name]"},
+		help:    "Show the environment variables",
+		maxArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			env, err := h.ShowEnv(args...)
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: env, columns: []string{"name", "value"}}
+			for _, key := range sortedKeys(env) {
+				r.rows = append(r.rows, []string{key, env[key]})
+			}
+			return r, nil
+		},
+	},
+	{
+		name: "ssl certs",
+		help: "List the loaded certificates",
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			list, err := h.ShowSSLCerts()
+			if err != nil {
+				return nil, err
+			}
+			r := &resultT{value: list, columns: []string{"file", "transaction"}}
+			for _, file := range list.Files {
+				r.rows = append(r.rows, []string{file, strconv.FormatBool(file == list.Transaction)})
+			}
+			return r, nil
+		},
+	},
+	{
+		name:    "ssl cert",
+		args:    []string{"<file>"},
+		help:    "Show the details of a certificate",
+		minArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			cert, err := h.ShowSSLCert(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return &resultT{value: cert}, nil
+		},
+	},
+	{
+		name:    "clear-counters",
+		args:    []string{"[all]"},
+		help:    "Clear the max counters, with all every counter is cleared",
+		maxArgs: 1,
+		run: func(h *haproxysocket.HaproxyInstace, args []string) (*resultT, error) {
+			if len(args) == 1 && args[0] != "all" {
+				return nil, errors.New("the only allowed argument is all")
+			}
+			return noResult(h.ClearCounters(len(args) == 1))
+		},
+	},
+}
+
+// findCommand returns the command matching the start of args and the remaining arguments
+func findCommand(args []string) (*commandT, []string) {
+	for i := range commands {
+		parts := strings.Split(commands[i].name, " ")
+		if len(args) < len(parts) {
+			continue
+		}
+		if strings.Join(args[:len(parts)], " ") == commands[i].name {
+			return &commands[i], args[len(parts):]
+		}
+	}
+	return nil, args
+}
+
+func (c *commandT) usage() string {
+	return strings.TrimSpace(c.name + " " + strings.Join(c.args, " "))
+}
+
+func (c *commandT) validate(args []string) error {
+	maxArgs := c.maxArgs
+	if maxArgs == 0 {
+		maxArgs = c.minArgs
+	}
+	if len(args) < c.minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return errors.New("usage: haproxyctl " + c.usage())
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var completionScripts = map[string]string{
+	"bash": `# Add to ~/.bashrc: source <(haproxyctl completion bash)
+_haproxyctl() {
+	local IFS=$'\n'
+	COMPREPLY=($(haproxyctl __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -F _haproxyctl haproxyctl
+`,
+	"zsh": `#compdef haproxyctl
+# Add to ~/.zshrc: source <(haproxyctl completion zsh)
+_haproxyctl() {
+	local -a candidates
+	candidates=("${(@f)$(haproxyctl __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+	compadd -a candidates
+}
+compdef _haproxyctl haproxyctl
+`,
+}
+
+// complete writes the completion candidates for the last word of words
+// Backend, server, frontend and table names are fetched live from the first target
+func complete(words []string, out io.Writer) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	opts, args, _ := parseFlags(words[:len(words)-1], io.Discard)
+	if strings.HasPrefix(current, "-") {
+		return
+	}
+
+	candidates := []string{}
+	command, commandArgs := findCommand(args)
+	if command == nil {
+		// Complete the command name
+		typed := strings.Join(args, " ")
+		seen := map[string]bool{}
+		names := []string{"completion"}
+		for _, c := range commands {
+			names = append(names, c.name)
+		}
+		for _, name := range names {
+			if typed != "" && !strings.HasPrefix(name, typed+" ") {
+				continue
+			}
+			next := strings.Split(strings.TrimPrefix(strings.TrimPrefix(name, typed), " "), " ")[0]
+			if !seen[next] {
+				seen[next] = true
+				candidates = append(candidates, next)
+			}
+		}
+		if typed == "completion" {
+			candidates = []string{"bash", "zsh"}
+		}
+	} else if len(command.kinds) > 0 {
+		kind := command.kinds[len(command.kinds)-1]
+		if len(commandArgs) < len(command.kinds) {
+			kind = command.kinds[len(commandArgs)]
+		}
+		candidates = completeKind(opts, kind, commandArgs)
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Fprintln(out, candidate)
+		}
+	}
+}
+
+func completeKind(opts optionsT, kind string, args []string) []string {
+	switch kind {
+	case argState:
+		return []string{"ready", "drain", "maint"}
+	case argHealth:
+		return []string{"up", "stopping", "down"}
+	case argOther:
+		return []string{}
+	}
+
+	config, err := readConfig(opts.config)
+	if err != nil {
+		return []string{}
+	}
+	targets, err := resolveTargets(opts.address, opts.target, config)
+	if err != nil || len(targets) == 0 {
+		return []string{}
+	}
+	h := targets[0].instance()
+
+	toReturn := []string{}
+	switch kind {
+	case argBackend:
+		backends, err := h.ShowBackend()
+		if err != nil {
+			return toReturn
+		}
+		for _, backend := range backends {
+			if backend["name"] != "" {
+				toReturn = append(toReturn, backend["name"])
+			}
+		}
+	case argServer, argFrontend:
+		stats, err := h.ShowStat()
+		if err != nil {
+			return toReturn
+		}
+		for _, row := range stats {
+			if kind == argFrontend && row["svname"] == "FRONTEND" {
+				toReturn = append(toReturn, row["pxname"])
+			}
+			// The backend is the argument before the server
+			if kind == argServer && len(args) > 0 && row["pxname"] == args[len(args)-1] && row["type"] == "2" {
+				toReturn = append(toReturn, row["svname"])
+			}
+		}
+	case argTable:
+		tables, err := h.ShowTables()
+		if err != nil {
+			return toReturn
+		}
+		for _, table := range tables {
+			toReturn = append(toReturn, table.Name)
+		}
+	}
+	return toReturn
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// configT is the config file, by default ~/.config/haproxyctl.json
+//
+//	{
+//	  "output": "table",
+//	  "targets": [
+//	    {"name": "lb1", "address": "unix:/var/run/haproxy.sock"},
+//	    {"name": "lb2", "address": "tcp:10.0.0.2:9999"}
+//	  ]
+//	}
+type configT struct {
+	Output  string    `json:"output"`
+	Targets []targetT `json:"targets"`
+}
+
+// targetT is a haproxy instance to execute the commands on
+type targetT struct {
+	Name    string `json:"name"`
+	Address string `json:"address"` // unix:<path>, tcp:<host>:<port>, a path or host:port
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "haproxyctl.json")
+}
+
+func readConfig(path string) (configT, error) {
+	config := configT{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// parseAddress converts an address into a network and address for haproxysocket.New
+func parseAddress(address string) (string, string) {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		return "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		return "tcp", strings.TrimPrefix(address, "tcp:")
+	case strings.HasPrefix(address, "/"), strings.HasPrefix(address, "."):
+		return "unix", address
+	case strings.Contains(address, ":"):
+		return "tcp", address
+	}
+	return "unix", address
+}
+
+// resolveTargets returns the instances to use
+// The addresses are taken from the first one that is set: the -address flag, the -target flag,
+// the HAPROXYCTL_ADDRESS environment variable, the targets in the config file or /var/run/haproxy.sock
+func resolveTargets(addressFlag, targetFlag string, config configT) ([]targetT, error) {
+	toReturn := []targetT{}
+	addresses := addressFlag
+	if addresses == "" && targetFlag == "" {
+		addresses = os.Getenv("HAPROXYCTL_ADDRESS")
+	}
+	if addresses != "" {
+		for _, address := range splitList(addresses) {
+			toReturn = append(toReturn, targetT{Name: address, Address: address})
+		}
+		return toReturn, nil
+	}
+
+	if targetFlag != "" {
+		for _, name := range splitList(targetFlag) {
+			found := false
+			for _, target := range config.Targets {
+				if name == target.Name || name == "all" {
+					toReturn = append(toReturn, target)
+					found = true
+				}
+			}
+			if !found {
+				return toReturn, errors.New("unknown target " + name)
+			}
+		}
+		return toReturn, nil
+	}
+
+	if len(config.Targets) > 0 {
+		return config.Targets, nil
+	}
+	return []targetT{{Name: "default", Address: "/var/run/haproxy.sock"}}, nil
+}
+
+func (t targetT) instance() *haproxysocket.HaproxyInstace {
+	return haproxysocket.New(parseAddress(t.Address))
+}
+
+func splitList(in string) []string {
+	toReturn := []string{}
+	for _, item := range strings.Split(in, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			toReturn = append(toReturn, item)
+		}
+	}
+	return toReturn
+}
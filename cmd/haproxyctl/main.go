@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// optionsT are the global flags
+type optionsT struct {
+	address string
+	target  string
+	output  string
+	columns string
+	config  string
+}
+
+func parseFlags(args []string, errOut io.Writer) (optionsT, []string, error) {
+	opts := optionsT{}
+	fs := flag.NewFlagSet("haproxyctl", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.StringVar(&opts.address, "address", "", "Comma separated haproxy socket addresses like unix:/var/run/haproxy.sock or tcp:127.0.0.1:9999, overwrites $HAPROXYCTL_ADDRESS")
+	fs.StringVar(&opts.target, "target", "", "Comma separated names of targets from the config file or all")
+	fs.StringVar(&opts.output, "o", "", "The output format: table, json or csv, overwrites $HAPROXYCTL_OUTPUT")
+	fs.StringVar(&opts.columns, "columns", "", "Comma separated columns to show in the table and csv output")
+	fs.StringVar(&opts.config, "config", defaultConfigPath(), "The config file")
+	fs.Usage = func() {
+		fmt.Fprintln(errOut, "Usage: haproxyctl [flags] <command> [args]")
+		fmt.Fprintln(errOut, "\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(errOut, "\nCommands:")
+		w := errOut
+		for _, c := range commands {
+			fmt.Fprintf(w, "  %-58v %v\n", c.usage(), c.help)
+		}
+		fmt.Fprintf(w, "  %-58v %v\n", "completion <bash|zsh>", "Print the shell completion script")
+	}
+	err := fs.Parse(args)
+	if err == nil && fs.NArg() == 0 {
+		fs.Usage()
+		err = flag.ErrHelp
+	}
+	return opts, fs.Args(), err
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, out, errOut io.Writer) int {
+	if len(args) > 0 && args[0] == "__complete" {
+		complete(args[1:], out)
+		return 0
+	}
+
+	opts, args, err := parseFlags(args, errOut)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	if args[0] == "completion" {
+		if len(args) != 2 {
+			fmt.Fprintln(errOut, "usage: haproxyctl completion <bash|zsh>")
+			return 2
+		}
+		script, ok := completionScripts[args[1]]
+		if !ok {
+			fmt.Fprintln(errOut, "unsupported shell "+args[1]+", use bash or zsh")
+			return 2
+		}
+		fmt.Fprint(out, script)
+		return 0
+	}
+
+	command, commandArgs := findCommand(args)
+	if command == nil {
+		fmt.Fprintln(errOut, "unknown command "+strings.Join(args, " ")+", see haproxyctl -h")
+		return 2
+	}
+	err = command.validate(commandArgs)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+
+	config, err := readConfig(opts.config)
+	if err != nil {
+		fmt.Fprintln(errOut, "unable to read config "+opts.config+": "+err.Error())
+		return 1
+	}
+	targets, err := resolveTargets(opts.address, opts.target, config)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+	output := opts.output
+	if output == "" {
+		output = os.Getenv("HAPROXYCTL_OUTPUT")
+	}
+	if output == "" {
+		output = config.Output
+	}
+	if output == "" {
+		output = "table"
+	}
+
+	results := make([]targetResultT, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target targetT) {
+			defer wg.Done()
+			result, err := command.run(target.instance(), commandArgs)
+			results[i] = targetResultT{target: target.Name, result: result, err: err}
+		}(i, target)
+	}
+	wg.Wait()
+
+	err = writeResults(out, errOut, output, splitList(opts.columns), results)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+	for _, result := range results {
+		if result.err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]string) []string {
+	toReturn := []string{}
+	for key := range m {
+		toReturn = append(toReturn, key)
+	}
+	sort.Strings(toReturn)
+	return toReturn
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// resultT is the output of a command on a single target
+type resultT struct {
+	value interface{} // Used for the json output
+
+	// Used for the table and csv output, if maps is set the rows are generated from the maps
+	columns []string
+	rows    [][]string
+	maps    []map[string]string
+}
+
+// mapsResult creates a result from rows like the "show stat" output
+// defaultColumns are shown in the table output, the csv output contains all columns
+func mapsResult(maps []map[string]string, defaultColumns ...string) *resultT {
+	return &resultT{value: maps, maps: maps, columns: defaultColumns}
+}
+
+// targetResultT is the output of a command on a target
+type targetResultT struct {
+	target string
+	result *resultT
+	err    error
+}
+
+// table returns the columns and rows of a result
+func (r *resultT) table(columns []string, all bool) ([]string, [][]string) {
+	if r.maps == nil {
+		return r.columns, r.rows
+	}
+	if len(columns) == 0 && !all {
+		columns = r.columns
+	}
+	if len(columns) == 0 {
+		// Use all keys, the names first
+		keys := map[string]bool{}
+		for _, row := range r.maps {
+			for key := range row {
+				keys[key] = true
+			}
+		}
+		for _, first := range []string{"pxname", "svname", "be_name", "srv_name"} {
+			if keys[first] {
+				columns = append(columns, first)
+				delete(keys, first)
+			}
+		}
+		rest := []string{}
+		for key := range keys {
+			rest = append(rest, key)
+		}
+		sort.Strings(rest)
+		columns = append(columns, rest...)
+	}
+	rows := [][]string{}
+	for _, row := range r.maps {
+		values := []string{}
+		for _, column := range columns {
+			values = append(values, row[column])
+		}
+		rows = append(rows, values)
+	}
+	return columns, rows
+}
+
+// writeResults writes the results in the output format, errors are written to errW
+// Results without a table representation are always written as json
+func writeResults(w, errW io.Writer, format string, columns []string, results []targetResultT) error {
+	multiple := len(results) > 1
+	for _, result := range results {
+		if result.result != nil && result.result.columns == nil && result.result.maps == nil {
+			format = "json"
+		}
+	}
+	switch format {
+	case "json":
+		var value interface{}
+		if multiple {
+			perTarget := map[string]interface{}{}
+			for _, result := range results {
+				switch {
+				case result.err != nil:
+					perTarget[result.target] = map[string]string{"error": result.err.Error()}
+				case result.result == nil:
+					perTarget[result.target] = map[string]bool{"ok": true}
+				default:
+					perTarget[result.target] = result.result.value
+				}
+			}
+			value = perTarget
+		} else if results[0].err != nil {
+			value = map[string]string{"error": results[0].err.Error()}
+		} else if results[0].result == nil {
+			value = map[string]bool{"ok": true}
+		} else {
+			value = results[0].result.value
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case "table", "csv":
+	default:
+		return errors.New("unknown output format " + format + ", use table, json or csv")
+	}
+
+	var header []string
+	rows := [][]string{}
+	for _, result := range results {
+		if result.err != nil {
+			if multiple {
+				fmt.Fprintln(errW, result.target+": "+result.err.Error())
+			} else {
+				fmt.Fprintln(errW, result.err.Error())
+			}
+			continue
+		}
+		if result.result == nil {
+			if format == "table" && multiple {
+				fmt.Fprintln(w, result.target+": ok")
+			} else if format == "table" {
+				fmt.Fprintln(w, "ok")
+			}
+			continue
+		}
+		resultColumns, resultRows := result.result.table(columns, format == "csv")
+		if header == nil {
+			header = resultColumns
+			if multiple {
+				header = append([]string{"target"}, header...)
+			}
+		}
+		for _, row := range resultRows {
+			if multiple {
+				row = append([]string{result.target}, row...)
+			}
+			rows = append(rows, row)
+		}
+	}
+	if header == nil {
+		return nil
+	}
+
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
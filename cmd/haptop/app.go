@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// statRow is a single proxy or server in the stats view
+type statRow struct {
+	kind    string // FE, BE, SRV or LSN
+	proxy   string
+	server  string
+	status  string
+	weight  string
+	scur    float64
+	qcur    float64
+	reqRate float64
+	inRate  float64
+	outRate float64
+	errRate float64 // The amount of 5xx responses and connection errors per second
+	errPct  float64 // errRate as percentage of the requests
+	check   string
+}
+
+// column is a column of the stats view
+type column struct {
+	title string
+	width int
+	text  func(r statRow) string
+	num   func(r statRow) float64 // nil for text columns
+}
+
+func rate(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.1fG", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+var columns = []column{
+	{title: "TYPE", width: 4, text: func(r statRow) string { return r.kind }},
+	{title: "PROXY", width: 20, text: func(r statRow) string { return r.proxy }},
+	{title: "SERVER", width: 16, text: func(r statRow) string { return r.server }},
+	{title: "STATUS", width: 9, text: func(r statRow) string { return r.status }},
+	{title: "WEIGHT", width: 6, text: func(r statRow) string { return r.weight }},
+	{title: "SCUR", width: 7, text: func(r statRow) string { return strconv.FormatFloat(r.scur, 'f', 0, 64) }, num: func(r statRow) float64 { return r.scur }},
+	{title: "QCUR", width: 6, text: func(r statRow) string { return strconv.FormatFloat(r.qcur, 'f', 0, 64) }, num: func(r statRow) float64 { return r.qcur }},
+	{title: "REQ/S", width: 8, text: func(r statRow) string { return rate(r.reqRate) }, num: func(r statRow) float64 { return r.reqRate }},
+	{title: "IN/S", width: 8, text: func(r statRow) string { return rate(r.inRate) }, num: func(r statRow) float64 { return r.inRate }},
+	{title: "OUT/S", width: 8, text: func(r statRow) string { return rate(r.outRate) }, num: func(r statRow) float64 { return r.outRate }},
+	{title: "ERR/S", width: 7, text: func(r statRow) string { return rate(r.errRate) }, num: func(r statRow) float64 { return r.errRate }},
+	{title: "ERR%", width: 6, text: func(r statRow) string { return strconv.FormatFloat(r.errPct, 'f', 1, 64) }, num: func(r statRow) float64 { return r.errPct }},
+	{title: "CHECK", width: 10, text: func(r statRow) string { return r.check }},
+}
+
+var statKinds = map[string]string{
+	"0": "FE",
+	"1": "BE",
+	"2": "SRV",
+	"3": "LSN",
+}
+
+// app contains the state of haptop
+type app struct {
+	h      *haproxysocket.HaproxyInstace
+	poller *haproxysocket.StatPollerT
+
+	rows, cols int
+	sessView   bool
+	stats      []statRow
+	sessions   []haproxysocket.SessionT
+	info       map[string]string
+	err        string
+	message    string
+
+	selected int
+	sortCol  int
+	reverse  bool
+	filter   string
+
+	// prompt is set while the user is typing, for example a filter or a weight
+	prompt      string
+	promptInput string
+	promptDone  func(input string)
+}
+
+func newApp(h *haproxysocket.HaproxyInstace) *app {
+	return &app{
+		h:       h,
+		poller:  h.StatPoller(),
+		info:    map[string]string{},
+		sortCol: 1,
+	}
+}
+
+// refresh reads the latest data from haproxy
+func (a *app) refresh() {
+	a.err = ""
+	if a.sessView {
+		sessions, err := a.h.ShowSess()
+		if err != nil {
+			a.err = err.Error()
+			return
+		}
+		a.sessions = sessions
+		return
+	}
+
+	snapshot := a.poller.Poll()
+	if snapshot.Err != nil {
+		a.err = snapshot.Error
+		return
+	}
+	a.info = snapshot.Info
+	a.stats = []statRow{}
+	for _, row := range snapshot.Rows {
+		v := row.Values
+		r := statRow{
+			kind:    statKinds[v["type"]],
+			proxy:   row.Proxy,
+			server:  row.Server,
+			status:  v["status"],
+			weight:  v["weight"],
+			scur:    float64(parseUint(v["scur"])),
+			qcur:    float64(parseUint(v["qcur"])),
+			inRate:  row.Rates["bin"],
+			outRate: row.Rates["bout"],
+			errRate: row.Rates["hrsp_5xx"] + row.Rates["econ"] + row.Rates["eresp"],
+			check:   strings.TrimPrefix(v["check_status"], "* "),
+		}
+		requests := row.Rates["req_tot"]
+		if v["req_tot"] == "" {
+			requests = row.Rates["stot"]
+		}
+		r.reqRate = requests
+		if requests > 0 {
+			r.errPct = r.errRate / requests * 100
+		}
+		a.stats = append(a.stats, r)
+	}
+}
+
+func parseUint(in string) uint64 {
+	i, _ := strconv.ParseUint(in, 10, 64)
+	return i
+}
+
+// visibleStats returns the filtered and sorted stats
+func (a *app) visibleStats() []statRow {
+	toReturn := []statRow{}
+	for _, r := range a.stats {
+		if a.filter == "" || strings.Contains(r.proxy+"/"+r.server+" "+r.status, a.filter) {
+			toReturn = append(toReturn, r)
+		}
+	}
+	col := columns[a.sortCol]
+	sort.SliceStable(toReturn, func(i, j int) bool {
+		var less bool
+		if col.num != nil {
+			// Numeric columns are sorted high to low by default
+			less = col.num(toReturn[i]) > col.num(toReturn[j])
+		} else {
+			less = col.text(toReturn[i]) < col.text(toReturn[j])
+		}
+		if a.reverse {
+			return !less
+		}
+		return less
+	})
+	return toReturn
+}
+
+// visibleSessions returns the filtered sessions, oldest first
+func (a *app) visibleSessions() []haproxysocket.SessionT {
+	toReturn := []haproxysocket.SessionT{}
+	for _, s := range a.sessions {
+		if a.filter == "" || strings.Contains(s.Source+" "+s.Frontend+" "+s.Backend+"/"+s.Server, a.filter) {
+			toReturn = append(toReturn, s)
+		}
+	}
+	sort.SliceStable(toReturn, func(i, j int) bool {
+		if a.reverse {
+			return toReturn[i].Age < toReturn[j].Age
+		}
+		return toReturn[i].Age > toReturn[j].Age
+	})
+	return toReturn
+}
+
+func (a *app) ask(prompt string, done func(input string)) {
+	a.prompt = prompt
+	a.promptInput = ""
+	a.promptDone = done
+}
+
+func (a *app) result(what string, err error) {
+	if err != nil {
+		a.message = what + " failed: " + err.Error()
+		return
+	}
+	a.message = what
+	a.refresh()
+}
+
+// handleKey handles a single key press, returns false if haptop should exit
+func (a *app) handleKey(key string) bool {
+	if key == keyCtrlC {
+		return false
+	}
+
+	if a.prompt != "" {
+		switch key {
+		case keyEnter:
+			done := a.promptDone
+			input := a.promptInput
+			a.prompt = ""
+			done(input)
+		case keyEsc:
+			a.prompt = ""
+		case keyBack:
+			if len(a.promptInput) > 0 {
+				a.promptInput = a.promptInput[:len(a.promptInput)-1]
+			}
+		default:
+			if len(key) == 1 {
+				a.promptInput += key
+			}
+		}
+		return true
+	}
+
+	a.message = ""
+	var amount int
+	if a.sessView {
+		amount = len(a.visibleSessions())
+	} else {
+		amount = len(a.visibleStats())
+	}
+
+	switch key {
+	case "q":
+		return false
+	case keyUp, "k":
+		if a.selected > 0 {
+			a.selected--
+		}
+	case keyDown, "j":
+		if a.selected < amount-1 {
+			a.selected++
+		}
+	case keyTab, "s":
+		a.sessView = !a.sessView
+		a.selected = 0
+		a.refresh()
+	case "o":
+		a.sortCol = (a.sortCol + 1) % len(columns)
+	case "r":
+		a.reverse = !a.reverse
+	case "/":
+		a.ask("filter", func(input string) {
+			a.filter = input
+			a.selected = 0
+		})
+	case "m", "d", "u", "w":
+		if a.sessView {
+			break
+		}
+		stats := a.visibleStats()
+		if a.selected >= len(stats) || stats[a.selected].kind != "SRV" {
+			a.message = "select a server first"
+			break
+		}
+		r := stats[a.selected]
+		name := r.proxy + "/" + r.server
+		switch key {
+		case "m":
+			a.result(name+" set to maint", a.h.Server(r.proxy, r.server).State("maint"))
+		case "d":
+			a.result(name+" set to drain", a.h.Server(r.proxy, r.server).State("drain"))
+		case "u":
+			a.result(name+" set to ready", a.h.Server(r.proxy, r.server).State("ready"))
+		case "w":
+			a.ask("weight of "+name, func(input string) {
+				a.result(name+" weight set to "+input, a.h.SetWeight(r.proxy, r.server, input))
+			})
+		}
+	case "x":
+		if !a.sessView {
+			break
+		}
+		sessions := a.visibleSessions()
+		if a.selected >= len(sessions) {
+			break
+		}
+		id := sessions[a.selected].ID
+		a.ask("shutdown session "+id+"? (y/n)", func(input string) {
+			if input == "y" {
+				a.result("session "+id+" shut down", a.h.ShutdownSession(id))
+			}
+		})
+	}
+	return true
+}
+
+func fit(in string, width int) string {
+	if len(in) > width {
+		return in[:width]
+	}
+	return in + strings.Repeat(" ", width-len(in))
+}
+
+// draw renders the screen
+func (a *app) draw() {
+	lines := []string{}
+	lines = append(lines, fmt.Sprintf("haptop - haproxy %v - up %v - conns %v - conn/s %v - sess/s %v - idle %v%%",
+		a.info["Version"], a.info["Uptime"], a.info["CurrConns"], a.info["ConnRate"], a.info["SessRate"], a.info["Idle_pct"]))
+	sortTitle := columns[a.sortCol].title
+	if a.sessView {
+		sortTitle = "AGE"
+	}
+	lines = append(lines, fmt.Sprintf("sort: %v reversed: %v filter: %q", sortTitle, a.reverse, a.filter))
+	status := a.message
+	if a.err != "" {
+		status = "error: " + a.err
+	}
+	lines = append(lines, status)
+
+	header := ""
+	body := []string{}
+	if a.sessView {
+		header = fit("ID", 16) + " " + fit("SOURCE", 22) + " " + fit("FRONTEND", 16) + " " + fit("BACKEND/SERVER", 28) + " " + fit("AGE", 10) + " " + fit("CPU", 10) + " " + fit("CALLS", 8)
+		for _, s := range a.visibleSessions() {
+			body = append(body, fit(s.ID, 16)+" "+fit(s.Source, 22)+" "+fit(s.Frontend, 16)+" "+fit(s.Backend+"/"+s.Server, 28)+" "+
+				fit(s.Age.Round(time.Second).String(), 10)+" "+fit(s.CPU.String(), 10)+" "+fit(strconv.FormatUint(s.Calls, 10), 8))
+		}
+	} else {
+		for i, col := range columns {
+			title := col.title
+			if i == a.sortCol {
+				title = "*" + title
+			}
+			header += fit(title, col.width) + " "
+		}
+		for _, r := range a.visibleStats() {
+			line := ""
+			for _, col := range columns {
+				line += fit(col.text(r), col.width) + " "
+			}
+			body = append(body, line)
+		}
+	}
+	lines = append(lines, "\x1b[7m"+fit(header, a.cols)+"\x1b[0m")
+
+	// Keep the selected row on screen
+	space := a.rows - len(lines) - 1
+	if a.selected >= len(body) {
+		a.selected = len(body) - 1
+	}
+	if a.selected < 0 {
+		a.selected = 0
+	}
+	offset := 0
+	if a.selected >= space {
+		offset = a.selected - space + 1
+	}
+	for i := offset; i < len(body) && i-offset < space; i++ {
+		line := fit(body[i], a.cols)
+		if i == a.selected {
+			line = "\x1b[1;44m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < a.rows-1 {
+		lines = append(lines, "")
+	}
+
+	footer := "q quit  j/k move  s sessions  / filter  o sort  r reverse  m maint  d drain  u ready  w weight"
+	if a.sessView {
+		footer = "q quit  j/k move  s stats  / filter  r reverse  x shutdown session"
+	}
+	if a.prompt != "" {
+		footer = a.prompt + ": " + a.promptInput
+	}
+	lines = append(lines, fit(footer, a.cols))
+
+	for i := range lines {
+		if !strings.Contains(lines[i], "\x1b") {
+			lines[i] = fit(lines[i], a.cols)
+		}
+	}
+	// In raw mode a newline doesn't return the cursor so \r\n is used
+	os.Stdout.WriteString("\x1b[H" + strings.Join(lines, "\r\n"))
+}
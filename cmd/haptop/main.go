@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+func main() {
+	network := flag.String("network", "unix", "The network of the haproxy socket, unix or tcp")
+	address := flag.String("address", "/var/run/haproxy.sock", "The address of the haproxy socket")
+	interval := flag.Duration("interval", 2*time.Second, "The time between refreshes")
+	flag.Parse()
+
+	h := haproxysocket.New(*network, *address)
+	a := newApp(h)
+
+	t, err := openTerminal()
+	if err != nil {
+		fmt.Println("Unable to switch the terminal to raw mode:", err)
+		os.Exit(1)
+	}
+	defer t.close()
+
+	keys := make(chan string)
+	go readKeys(keys)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	a.rows, a.cols = t.size()
+	a.refresh()
+	a.draw()
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !a.handleKey(key) {
+				return
+			}
+		case <-ticker.C:
+			a.rows, a.cols = t.size()
+			a.refresh()
+		}
+		a.draw()
+	}
+}
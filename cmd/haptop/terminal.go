@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// The keys that are not a single printable character
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyEnter = "enter"
+	keyEsc   = "esc"
+	keyBack  = "backspace"
+	keyCtrlC = "ctrl-c"
+	keyTab   = "tab"
+)
+
+// terminal switches the terminal into raw mode and restores it on close
+type terminal struct {
+	saved string
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func openTerminal() (*terminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	_, err = stty("raw", "-echo")
+	if err != nil {
+		return nil, err
+	}
+	// Switch to the alternate screen and hide the cursor
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	return &terminal{saved: saved}, nil
+}
+
+func (t *terminal) close() {
+	os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+	stty(t.saved)
+}
+
+// size returns the amount of rows and columns of the terminal
+func (t *terminal) size() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		parts := strings.Fields(out)
+		if len(parts) == 2 {
+			rows, err1 := strconv.Atoi(parts[0])
+			cols, err2 := strconv.Atoi(parts[1])
+			if err1 == nil && err2 == nil && rows > 0 && cols > 0 {
+				return rows, cols
+			}
+		}
+	}
+	return 24, 80
+}
+
+// readKeys reads the keys from stdin and sends them to keys
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		in := buf[:n]
+		for len(in) > 0 {
+			switch {
+			case len(in) >= 3 && in[0] == 0x1b && in[1] == '[' && in[2] == 'A':
+				keys <- keyUp
+				in = in[3:]
+			case len(in) >= 3 && in[0] == 0x1b && in[1] == '[' && in[2] == 'B':
+				keys <- keyDown
+				in = in[3:]
+			case len(in) >= 3 && in[0] == 0x1b && in[1] == '[':
+				// Other escape sequences are ignored
+				in = in[3:]
+			case in[0] == 0x1b:
+				keys <- keyEsc
+				in = in[1:]
+			case in[0] == '\r' || in[0] == '\n':
+				keys <- keyEnter
+				in = in[1:]
+			case in[0] == 0x7f || in[0] == 0x08:
+				keys <- keyBack
+				in = in[1:]
+			case in[0] == 0x03:
+				keys <- keyCtrlC
+				in = in[1:]
+			case in[0] == '\t':
+				keys <- keyTab
+				in = in[1:]
+			default:
+				keys <- string(in[:1])
+				in = in[1:]
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ShowErrors report last request and response errors for each proxy
@@ -141,12 +142,19 @@ func (h *HaproxyInstace) Server(backend, server string) *ServerT {
 // Optionnaly, the port can be changed using the 'port' parameter.
 // Note that changing the port also support switching from/to port mapping
 // (notation with +X or -Y), only if a port is configured for the health check.
+// The port is a number or a port mapping like +10 or -10, the "port" keyword is added by this function
+// For backwards compatibility the port can also be given with the keyword, like "port 80"
 func (s *ServerT) Addr(addr string, port ...string) error {
 	query := "addr " + addr
 	switch len(port) {
 	case 0:
 	case 1:
-		query = query + " " + port[0]
+		value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(port[0]), "port "))
+		number := strings.TrimLeft(value, "+-")
+		if _, err := strconv.ParseUint(number, 10, 16); err != nil {
+			return errors.New("port must be a number or a port mapping like +10")
+		}
+		query = query + " port " + value
 	default:
 		return errors.New("There can only be 0 or 1 ports defined")
 	}
@@ -344,18 +352,28 @@ func (h *HaproxyInstace) SetWeight(backend, server, setTo string) error {
 
 // SessionT is the response data when asking for the sessions
 type SessionT struct {
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	Source  string `json:"source"`
-	CPU     string `json:"cpu"`
-	Latency string `json:"latency"`
-	Age     string `json:"age"`
-	RawRes  string `json:"rawRes"`
-	Calls   string `json:"calls"`
-	Expire  string `json:"expire"`
-}
-
-// ShowSess report the list of current sessions or dump this session
+	ID       string        `json:"id"`
+	Type     string        `json:"type"` // The protocol, for example tcpv4
+	Source   string        `json:"source"`
+	Frontend string        `json:"frontend"`
+	Backend  string        `json:"backend"`
+	Server   string        `json:"server"`
+	TS       string        `json:"ts"` // The stream state flags
+	Epoch    uint64        `json:"epoch"`
+	CPU      time.Duration `json:"cpu"`
+	Latency  time.Duration `json:"latency"`
+	Age      time.Duration `json:"age"`
+	Calls    uint64        `json:"calls"`
+	Expire   time.Duration `json:"expire"`
+	Request  SessChannelT  `json:"request"`  // rq[...]
+	Response SessChannelT  `json:"response"` // rp[...]
+	Front    SessConnT     `json:"front"`    // s0=[...] or scf=[...] in newer haproxy versions
+	Back     SessConnT     `json:"back"`     // s1=[...] or scb=[...] in newer haproxy versions
+	RawRes   string        `json:"rawRes"`
+}
+
+// ShowSess report the list of current sessions
+// For all details of a session use ShowSessDetail
 func (h *HaproxyInstace) ShowSess() ([]SessionT, error) {
 	toReturn := []SessionT{}
 	out, err := h.q("show sess")
@@ -363,42 +381,16 @@ func (h *HaproxyInstace) ShowSess() ([]SessionT, error) {
 		return toReturn, err
 	}
 	out = strings.TrimSpace(out)
+	if out == "" {
+		return toReturn, nil
+	}
 
 	lines := strings.Split(out, "\n")
 	for _, line := range lines {
-		toAdd := SessionT{RawRes: line}
-		items := strings.Split(line, " ")
-		for i, item := range items {
-			if i == 0 && len(item) > 0 {
-				toAdd.ID = strings.Replace(item, ":", "", 1)
-				continue
-			}
-
-			nameAndVal := strings.Split(item, "=")
-			if len(nameAndVal) < 2 {
-				continue
-			}
-			name := nameAndVal[0]
-			v := nameAndVal[1]
-
-			switch name {
-			case "proto":
-				toAdd.Type = v
-			case "src":
-				toAdd.Source = v
-			case "age":
-				toAdd.Age = v
-			case "calls":
-				toAdd.Calls = v
-			case "cpu":
-				toAdd.CPU = v
-			case "lat":
-				toAdd.Latency = v
-			case "exp":
-				toAdd.Expire = v
-			}
+		if !strings.HasPrefix(line, "0x") {
+			continue
 		}
-		toReturn = append(toReturn, toAdd)
+		toReturn = append(toReturn, parseSessLine(line))
 	}
 
 	return toReturn, nil
@@ -443,11 +435,6 @@ func (h *HaproxyInstace) SetTable(id string) error {
 	return nil
 }
 
-// ShowTable report table usage stats or dump this table's contents
-func (h *HaproxyInstace) ShowTable(id string) error {
-	return nil
-}
-
 // DisableFrontend temporarily disable specific frontend
 func (h *HaproxyInstace) DisableFrontend(frontend string) error {
 	if frontend == "" {
@@ -493,38 +480,6 @@ func (h *HaproxyInstace) SetMaxconnFrontend(frontend string, maxConn uint) error
 	return errors.New(out)
 }
 
-// ShowServersState dump volatile server information (for backend)
-func (h *HaproxyInstace) ShowServersState(backend string) ([]map[string]string, error) {
-	toReturn := []map[string]string{}
-	if backend == "" {
-		return toReturn, errors.New("backend can't be empty")
-	}
-	out, err := h.q("show servers state " + backend)
-	if err != nil {
-		return nil, err
-	}
-
-	if strings.Contains(out, "Can't find backend") {
-		return toReturn, errors.New(out)
-	}
-
-	lines := strings.Split(out, "\n")
-	if len(lines) == 0 {
-		return toReturn, errors.New("No output data")
-	}
-	if !strings.HasPrefix(lines[0], "1") {
-		return toReturn, errors.New("unsupported \"show servers state ...\" output, only support version 1, recieved version " + lines[0])
-	}
-
-	out = strings.Join(lines[1:], "\n")
-	toReturn, err = csvToArrMap(out, " ")
-	if err != nil {
-		return toReturn, err
-	}
-
-	return toReturn, nil
-}
-
 // ShowBackend list backends in the current running config
 func (h *HaproxyInstace) ShowBackend() ([]map[string]string, error) {
 	return h.qMap("show backend")
@@ -592,7 +547,51 @@ func (h *HaproxyInstace) ShowStatResolvers(id ...string) ([]map[string]string, e
 	default:
 		return []map[string]string{}, errors.New("There can't be more than 1 IDs")
 	}
-	return h.qMap(toEx)
+	out, err := h.q(toEx)
+	if err != nil {
+		return []map[string]string{}, err
+	}
+	return parseStatResolvers(out)
+}
+
+// parseStatResolvers parses the output of "show stat resolvers", every name server becomes 1 entry
+// with the keys "resolvers" and "nameserver" next to its counters
+func parseStatResolvers(out string) ([]map[string]string, error) {
+	toReturn := []map[string]string{}
+	if strings.TrimSpace(out) == "" {
+		return toReturn, nil
+	}
+
+	// The output looks like:
+	// Resolvers section mydns
+	//  nameserver dns1:
+	//   sent:        8
+	//   snd_error:   0
+	section := ""
+	var current map[string]string
+	for _, line := range strings.Split(out, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "Resolvers section "):
+			section = strings.TrimPrefix(trimmed, "Resolvers section ")
+			current = nil
+		case strings.HasPrefix(trimmed, "nameserver "):
+			current = map[string]string{
+				"resolvers":  section,
+				"nameserver": strings.TrimSuffix(strings.TrimPrefix(trimmed, "nameserver "), ":"),
+			}
+			toReturn = append(toReturn, current)
+		default:
+			parts := strings.SplitN(trimmed, ":", 2)
+			if current == nil || len(parts) != 2 {
+				return []map[string]string{}, errors.New(out)
+			}
+			current[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return toReturn, nil
 }
 
 // SetMaxconnGlobal change the per-process maxconn setting
@@ -685,24 +684,86 @@ func (h *HaproxyInstace) GetACL() error {
 	return nil
 }
 
-// ShowACL report available acls or dump an acl's contents
-func (h *HaproxyInstace) ShowACL(id string) error {
-	return nil
+// ACLEntryT is a single pattern of an acl
+type ACLEntryT struct {
+	ID      string `json:"id"` // The entry reference, can be used as #<ref> in the other acl functions
+	Pattern string `json:"pattern"`
+}
+
+// ShowACL dump an acl's contents
+func (h *HaproxyInstace) ShowACL(aclID string) ([]ACLEntryT, error) {
+	toReturn := []ACLEntryT{}
+	if aclID == "" {
+		return toReturn, errors.New("aclID can't be empty")
+	}
+	out, err := h.q("show acl " + aclID)
+	if err != nil {
+		return toReturn, err
+	}
+	if out == "" {
+		return toReturn, nil
+	}
+
+	lines := strings.Split(out, "\n")
+	for _, line := range lines {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "0x") {
+			return []ACLEntryT{}, errors.New(out)
+		}
+		toReturn = append(toReturn, ACLEntryT{
+			ID:      parts[0],
+			Pattern: parts[1],
+		})
+	}
+
+	return toReturn, nil
 }
 
 // AddMap add map entry
-func (h *HaproxyInstace) AddMap() error {
-	return nil
+// mapID can be the map file name or #<id>
+func (h *HaproxyInstace) AddMap(mapID, key, value string) error {
+	if mapID == "" || key == "" {
+		return errors.New("mapID and key can't be empty")
+	}
+	out, err := h.q("add map " + mapID + " " + key + " " + value)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return nil
+	}
+	return errors.New(out)
 }
 
 // ClearMap clear the content of this map
-func (h *HaproxyInstace) ClearMap() error {
-	return nil
+func (h *HaproxyInstace) ClearMap(mapID string) error {
+	if mapID == "" {
+		return errors.New("mapID can't be empty")
+	}
+	out, err := h.q("clear map " + mapID)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return nil
+	}
+	return errors.New(out)
 }
 
 // DelMap delete map entry
-func (h *HaproxyInstace) DelMap() error {
-	return nil
+// key can also be #<ref> to delete a specific entry
+func (h *HaproxyInstace) DelMap(mapID, key string) error {
+	if mapID == "" || key == "" {
+		return errors.New("mapID and key can't be empty")
+	}
+	out, err := h.q("del map " + mapID + " " + key)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return nil
+	}
+	return errors.New(out)
 }
 
 // GetMap report the keys and values matching a sample for a map
@@ -711,13 +772,60 @@ func (h *HaproxyInstace) GetMap() error {
 }
 
 // SetMap modify map entry
-func (h *HaproxyInstace) SetMap() error {
-	return nil
+// mapID can be the map file name or #<id>, key can also be #<ref> to modify a specific entry
+func (h *HaproxyInstace) SetMap(mapID, key, value string) error {
+	if mapID == "" || key == "" {
+		return errors.New("mapID and key can't be empty")
+	}
+	out, err := h.q("set map " + mapID + " " + key + " " + value)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return nil
+	}
+	return errors.New(out)
 }
 
-// ShowMap report available maps or dump a map's contents
-func (h *HaproxyInstace) ShowMap(id string) error {
-	return nil
+// MapEntryT is a single entry of a map
+type MapEntryT struct {
+	ID    string `json:"id"` // The entry reference, can be used as #<ref> in the other map functions
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ShowMap dump a map's contents
+func (h *HaproxyInstace) ShowMap(mapID string) ([]MapEntryT, error) {
+	toReturn := []MapEntryT{}
+	if mapID == "" {
+		return toReturn, errors.New("mapID can't be empty")
+	}
+	out, err := h.q("show map " + mapID)
+	if err != nil {
+		return toReturn, err
+	}
+	if out == "" {
+		return toReturn, nil
+	}
+
+	lines := strings.Split(out, "\n")
+	for _, line := range lines {
+		// Entries with an empty value don't have a value part
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) < 2 || !strings.HasPrefix(parts[0], "0x") {
+			return []MapEntryT{}, errors.New(out)
+		}
+		toAdd := MapEntryT{
+			ID:  parts[0],
+			Key: parts[1],
+		}
+		if len(parts) == 3 {
+			toAdd.Value = parts[2]
+		}
+		toReturn = append(toReturn, toAdd)
+	}
+
+	return toReturn, nil
 }
 
 // PoolT is the data from 1 pool
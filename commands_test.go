@@ -0,0 +1,151 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShowACL(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		expected  []ACLEntryT
+		expectErr bool
+	}{
+		{
+			name: "patterns",
+			out: "0x55d4c7e4b000 10.0.0.0/8\n" +
+				"0x55d4c7e4b080 192.168.0.0/16\n" +
+				"0x55d4c7e4b100 /admin with spaces\n",
+			expected: []ACLEntryT{
+				{ID: "0x55d4c7e4b000", Pattern: "10.0.0.0/8"},
+				{ID: "0x55d4c7e4b080", Pattern: "192.168.0.0/16"},
+				{ID: "0x55d4c7e4b100", Pattern: "/admin with spaces"},
+			},
+		},
+		{
+			name:     "empty acl",
+			out:      "",
+			expected: []ACLEntryT{},
+		},
+		{
+			name:      "unknown acl",
+			out:       "Unknown ACL identifier. Please use #<id> or <file>.\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := cannedInstance(t, map[string]string{"show acl #0": test.out})
+			entries, err := h.ShowACL("#0")
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("got %+v, expected %+v", entries, test.expected)
+			}
+		})
+	}
+}
+
+func TestServerAddr(t *testing.T) {
+	tests := []struct {
+		name          string
+		port          []string
+		expectedQuery string
+		expectErr     bool
+	}{
+		{name: "without port", expectedQuery: "set server be_app/app1 addr 10.0.0.9"},
+		{name: "port number", port: []string{"8080"}, expectedQuery: "set server be_app/app1 addr 10.0.0.9 port 8080"},
+		{name: "port with keyword", port: []string{"port 8080"}, expectedQuery: "set server be_app/app1 addr 10.0.0.9 port 8080"},
+		{name: "port mapping", port: []string{"+10"}, expectedQuery: "set server be_app/app1 addr 10.0.0.9 port +10"},
+		{name: "port mapping with keyword", port: []string{"port -10"}, expectedQuery: "set server be_app/app1 addr 10.0.0.9 port -10"},
+		{name: "invalid port", port: []string{"http"}, expectErr: true},
+		{name: "port out of range", port: []string{"port 70000"}, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responses := map[string]string{}
+			if test.expectedQuery != "" {
+				responses[test.expectedQuery] = "IP changed from '10.0.0.1' to '10.0.0.9' by 'stats socket command'"
+			}
+			err := cannedInstance(t, responses).Server("be_app", "app1").Addr("10.0.0.9", test.port...)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestShowMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		expected  []MapEntryT
+		expectErr bool
+	}{
+		{
+			name: "entries",
+			out: "0x55d4c7e4c000 example.com be_app\n" +
+				"0x55d4c7e4c080 static.example.com be_static with spaces\n",
+			expected: []MapEntryT{
+				{ID: "0x55d4c7e4c000", Key: "example.com", Value: "be_app"},
+				{ID: "0x55d4c7e4c080", Key: "static.example.com", Value: "be_static with spaces"},
+			},
+		},
+		{
+			name: "empty value",
+			out: "0x55d4c7e4c000 example.com \n" +
+				"0x55d4c7e4c080 other.example.com\n" +
+				"0x55d4c7e4c100 static.example.com be_static\n",
+			expected: []MapEntryT{
+				{ID: "0x55d4c7e4c000", Key: "example.com"},
+				{ID: "0x55d4c7e4c080", Key: "other.example.com"},
+				{ID: "0x55d4c7e4c100", Key: "static.example.com", Value: "be_static"},
+			},
+		},
+		{
+			name:     "empty map",
+			out:      "",
+			expected: []MapEntryT{},
+		},
+		{
+			name:      "unknown map",
+			out:       "Unknown map identifier. Please use #<id> or <file>.\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := cannedInstance(t, map[string]string{"show map #0": test.out})
+			entries, err := h.ShowMap("#0")
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("got %+v, expected %+v", entries, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package haproxysocket
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// CrtListEntryT is a single line of a crt-list
+type CrtListEntryT struct {
+	Cert       string   `json:"cert"`
+	Line       int      `json:"line"`       // The line number, only set when listing with line numbers
+	SSLOptions []string `json:"sslOptions"` // The words between [ and ], for example ["alpn", "h2", "verify", "required"]
+	SNIFilters []string `json:"sniFilters"` // For example "example.com" or "!www.example.com"
+}
+
+// String returns the entry in the crt-list format: <cert> [<ssl options>] [<sni filters>]
+func (e CrtListEntryT) String() string {
+	parts := []string{e.Cert}
+	if len(e.SSLOptions) > 0 {
+		parts = append(parts, "["+strings.Join(e.SSLOptions, " ")+"]")
+	}
+	parts = append(parts, e.SNIFilters...)
+	return strings.Join(parts, " ")
+}
+
+// parseCrtListLine parses a crt-list line like: cert.pem:2 [alpn h2 verify none] example.com !www.example.com
+func parseCrtListLine(line string, lineNumbers bool) CrtListEntryT {
+	toReturn := CrtListEntryT{
+		SSLOptions: []string{},
+		SNIFilters: []string{},
+	}
+
+	rest := ""
+	parts := strings.SplitN(line, " ", 2)
+	toReturn.Cert = parts[0]
+	if len(parts) == 2 {
+		rest = strings.TrimSpace(parts[1])
+	}
+	if lineNumbers {
+		if i := strings.LastIndex(toReturn.Cert, ":"); i > 0 {
+			n, err := strconv.Atoi(toReturn.Cert[i+1:])
+			if err == nil {
+				toReturn.Line = n
+				toReturn.Cert = toReturn.Cert[:i]
+			}
+		}
+	}
+
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end > 0 {
+			// Older haproxy versions separate the options using a comma
+			options := strings.Replace(rest[1:end], ",", " ", -1)
+			toReturn.SSLOptions = append(toReturn.SSLOptions, strings.Fields(options)...)
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+	}
+	toReturn.SNIFilters = append(toReturn.SNIFilters, strings.Fields(rest)...)
+
+	return toReturn
+}
+
+// ShowSSLCrtLists list all crt-list files
+func (h *HaproxyInstace) ShowSSLCrtLists() ([]string, error) {
+	toReturn := []string{}
+	out, err := h.q("show ssl crt-list")
+	if err != nil {
+		return toReturn, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		toReturn = append(toReturn, line)
+	}
+	return toReturn, nil
+}
+
+// ShowSSLCrtList dump the entries of a crt-list, lineNumbers adds the line number to every entry
+func (h *HaproxyInstace) ShowSSLCrtList(list string, lineNumbers bool) ([]CrtListEntryT, error) {
+	toReturn := []CrtListEntryT{}
+	if list == "" {
+		return toReturn, errors.New("list can't be empty")
+	}
+
+	toEx := "show ssl crt-list "
+	if lineNumbers {
+		toEx = toEx + "-n "
+	}
+	out, err := h.q(toEx + list)
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "#") {
+		return toReturn, errors.New(out)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		toReturn = append(toReturn, parseCrtListLine(line, lineNumbers))
+	}
+	return toReturn, nil
+}
+
+// AddSSLCrtList add an entry to a crt-list, the certificate must already be loaded (see NewSSLCert)
+// The Line field of the entry is ignored
+func (h *HaproxyInstace) AddSSLCrtList(list string, entry CrtListEntryT) error {
+	if list == "" || entry.Cert == "" {
+		return errors.New("list and entry.Cert can't be empty")
+	}
+
+	out, err := h.qPayload("add ssl crt-list "+list, entry.String())
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "Success!")
+}
+
+// DelSSLCrtList delete an entry from a crt-list
+// line is only required if the certificate is used multiple times in the list, use 0 to leave it out
+func (h *HaproxyInstace) DelSSLCrtList(list, cert string, line int) error {
+	if list == "" || cert == "" {
+		return errors.New("list and cert can't be empty")
+	}
+	if line > 0 {
+		cert = cert + ":" + strconv.Itoa(line)
+	}
+	out, err := h.q("del ssl crt-list " + list + " " + cert)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "deleted in crtlist")
+}
@@ -0,0 +1,153 @@
+package haproxysocket
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The kinds of drift
+const (
+	DriftServerState = "server_state" // The admin state of a server (ready, drain or maint)
+	DriftWeight      = "weight"
+	DriftAddr        = "addr"
+	DriftMaxconn     = "maxconn"
+	DriftMap         = "map"
+	DriftACL         = "acl"
+)
+
+// DriftMissing is used as value when something doesn't exist on a node
+const DriftMissing = "<missing>"
+
+// DriftOptsT are the options for Drift
+type DriftOptsT struct {
+	Maps []string // The maps to compare, by file name or #<id>
+	ACLs []string // The acls to compare, by file name or #<id>
+}
+
+// DriftT is a single difference between the nodes
+type DriftT struct {
+	Kind   string            `json:"kind"`
+	Key    string            `json:"key"`    // For example backend/server, map/key or acl/pattern
+	Values map[string]string `json:"values"` // The value per node name, see ClusterT.Names
+}
+
+// Drift compares the runtime state of all nodes and returns the differences
+// Compared are the server admin states, weights, addresses, maxconn settings and the contents of opts.Maps and opts.ACLs
+func (c *ClusterT) Drift(opts DriftOptsT) ([]DriftT, error) {
+	toReturn := []DriftT{}
+	if len(c.Nodes) < 2 {
+		return toReturn, errors.New("need at least 2 nodes to compare")
+	}
+
+	states := make([]map[string]string, len(c.Nodes))
+	result := c.each(func(i int, h *HaproxyInstace) error {
+		state, err := driftState(h, opts)
+		states[i] = state
+		return err
+	})
+	if failed := result.Failed(); len(failed) > 0 {
+		errs := []string{}
+		for _, node := range failed {
+			errs = append(errs, node.Node+": "+node.Error)
+		}
+		return toReturn, errors.New(strings.Join(errs, ", "))
+	}
+
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, state := range states {
+		for key := range state {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := map[string]string{}
+		different := false
+		for i, state := range states {
+			value, ok := state[key]
+			if !ok {
+				value = DriftMissing
+			}
+			values[c.NodeName(i)] = value
+			if i > 0 && value != values[c.NodeName(0)] {
+				different = true
+			}
+		}
+		if !different {
+			continue
+		}
+		parts := strings.SplitN(key, "|", 2)
+		toReturn = append(toReturn, DriftT{
+			Kind:   parts[0],
+			Key:    parts[1],
+			Values: values,
+		})
+	}
+
+	return toReturn, nil
+}
+
+// driftState collects the state of a single node as "<kind>|<key>" to value
+func driftState(h *HaproxyInstace, opts DriftOptsT) (map[string]string, error) {
+	toReturn := map[string]string{}
+
+	servers, err := h.ShowServersState()
+	if err != nil {
+		return toReturn, err
+	}
+	for _, s := range servers {
+		name := s.Backend + "/" + s.Server
+		toReturn[DriftServerState+"|"+name] = s.AdminState.Forced()
+		toReturn[DriftWeight+"|"+name] = strconv.Itoa(s.UWeight)
+		toReturn[DriftAddr+"|"+name] = s.Addr + ":" + strconv.Itoa(s.Port)
+	}
+
+	stats, err := h.ShowStat()
+	if err != nil {
+		return toReturn, err
+	}
+	for _, row := range stats {
+		toReturn[DriftMaxconn+"|"+row["pxname"]+"/"+row["svname"]] = row["slim"]
+	}
+
+	info, err := h.ShowInfo()
+	if err != nil {
+		return toReturn, err
+	}
+	toReturn[DriftMaxconn+"|global"] = info["Maxconn"]
+
+	for _, mapID := range opts.Maps {
+		entries, err := h.ShowMap(mapID)
+		if err != nil {
+			return toReturn, err
+		}
+		for _, entry := range entries {
+			key := DriftMap + "|" + mapID + "/" + entry.Key
+			if value, ok := toReturn[key]; ok {
+				// The same key can be in a map multiple times
+				toReturn[key] = value + "," + entry.Value
+				continue
+			}
+			toReturn[key] = entry.Value
+		}
+	}
+
+	for _, aclID := range opts.ACLs {
+		entries, err := h.ShowACL(aclID)
+		if err != nil {
+			return toReturn, err
+		}
+		for _, entry := range entries {
+			toReturn[DriftACL+"|"+aclID+"/"+entry.Pattern] = "present"
+		}
+	}
+
+	return toReturn, nil
+}
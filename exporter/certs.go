@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"sort"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// CertMetrics converts a report of haproxysocket.CertMonitorT to metrics, write them using WriteMetrics, for example:
+// exporter.WriteMetrics(w, e.CertMetrics(monitor.Last()))
+// The Namespace, Include and Exclude of the exporter are applied
+func (e *ExporterT) CertMetrics(report haproxysocket.CertReportT) []MetricT {
+	c := e.newCollection()
+
+	files := []string{}
+	for file := range report.Certs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		notAfter := report.Certs[file].NotAfter
+		if notAfter.IsZero() {
+			continue
+		}
+		c.add("ssl_cert_not_after_seconds", "Expiry date of the certificate as unix timestamp", Gauge, map[string]string{"file": file}, float64(notAfter.Unix()))
+	}
+
+	counts := map[string]float64{
+		haproxysocket.CertEventExpiring: 0,
+		haproxysocket.CertEventExpired:  0,
+		haproxysocket.CertEventPending:  0,
+		haproxysocket.CertEventMismatch: 0,
+		haproxysocket.CertEventError:    0,
+	}
+	for _, event := range report.Events {
+		counts[event.Kind]++
+	}
+	kinds := []string{}
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		c.add("ssl_cert_problems", "Amount of certificate problems found by the last check", Gauge, map[string]string{"kind": kind}, counts[kind])
+	}
+
+	return c.list()
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+func TestCertMetrics(t *testing.T) {
+	e := New(haproxysocket.New("unix", "/nonexistent.sock"))
+	report := haproxysocket.CertReportT{
+		Time: time.Unix(1700000000, 0),
+		Certs: map[string]haproxysocket.SSLCertT{
+			"/etc/haproxy/certs/b.pem":          {NotAfter: time.Unix(1800000000, 0)},
+			"/etc/haproxy/certs/\"quoted\".pem": {NotAfter: time.Unix(1900000000, 0)},
+			"/etc/haproxy/certs/empty.pem":      {},
+		},
+		Events: []haproxysocket.CertEventT{
+			{Kind: haproxysocket.CertEventExpired, File: "/etc/haproxy/certs/b.pem"},
+			{Kind: haproxysocket.CertEventMismatch, File: "/etc/haproxy/certs/c.pem"},
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := WriteMetrics(buf, e.CertMetrics(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP haproxy_ssl_cert_not_after_seconds Expiry date of the certificate as unix timestamp
+# TYPE haproxy_ssl_cert_not_after_seconds gauge
+haproxy_ssl_cert_not_after_seconds{file="/etc/haproxy/certs/\"quoted\".pem"} 1900000000
+haproxy_ssl_cert_not_after_seconds{file="/etc/haproxy/certs/b.pem"} 1800000000
+# HELP haproxy_ssl_cert_problems Amount of certificate problems found by the last check
+# TYPE haproxy_ssl_cert_problems gauge
+haproxy_ssl_cert_problems{kind="error"} 0
+haproxy_ssl_cert_problems{kind="expired"} 1
+haproxy_ssl_cert_problems{kind="expiring"} 0
+haproxy_ssl_cert_problems{kind="mismatch"} 1
+haproxy_ssl_cert_problems{kind="pending"} 0
+`
+	if buf.String() != expected {
+		t.Errorf("got\n%v\nexpected\n%v", buf.String(), expected)
+	}
+}
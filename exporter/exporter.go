@@ -0,0 +1,212 @@
+// Package exporter exposes the haproxy runtime statistics in the prometheus text format
+package exporter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// The sources metrics are collected from
+const (
+	SourceStat      = "stat"      // "show stat", haproxy_frontend_*, haproxy_backend_*, haproxy_server_* and haproxy_listener_*
+	SourceInfo      = "info"      // "show info", haproxy_process_*
+	SourcePools     = "pools"     // "show pools", haproxy_pool_*
+	SourceResolvers = "resolvers" // "show stat resolvers", haproxy_resolver_*
+	SourceTables    = "tables"    // "show table", haproxy_sticktable_*
+)
+
+// AllSources contains all sources
+var AllSources = []string{SourceStat, SourceInfo, SourcePools, SourceResolvers, SourceTables}
+
+// The metric types
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// SampleT is a single value of a metric
+type SampleT struct {
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// MetricT is a metric with all its samples
+type MetricT struct {
+	Name    string    `json:"name"`
+	Help    string    `json:"help"`
+	Type    string    `json:"type"`
+	Samples []SampleT `json:"samples"`
+}
+
+// ExporterT collects the metrics of a haproxy instance, create one using New
+type ExporterT struct {
+	h *haproxysocket.HaproxyInstace
+
+	Namespace string   // The prefix of all metrics, defaults to haproxy
+	Sources   []string // The sources to collect, defaults to AllSources
+	Include   []string // Only export metrics starting with one of these names (without namespace), exports everything if empty
+	Exclude   []string // Don't export metrics starting with one of these names (without namespace)
+}
+
+// New creates an exporter for a haproxy instance
+func New(h *haproxysocket.HaproxyInstace) *ExporterT {
+	return &ExporterT{
+		h:         h,
+		Namespace: "haproxy",
+		Sources:   AllSources,
+	}
+}
+
+// collection is used to build the metrics of a single scrape
+type collection struct {
+	e       *ExporterT
+	order   []string
+	metrics map[string]*MetricT
+}
+
+func (e *ExporterT) newCollection() *collection {
+	return &collection{
+		e:       e,
+		order:   []string{},
+		metrics: map[string]*MetricT{},
+	}
+}
+
+// list returns the metrics in the order they were added
+func (c *collection) list() []MetricT {
+	toReturn := []MetricT{}
+	for _, name := range c.order {
+		toReturn = append(toReturn, *c.metrics[name])
+	}
+	return toReturn
+}
+
+func (c *collection) add(name, help, metricType string, labels map[string]string, value float64) {
+	if !c.e.selected(name) {
+		return
+	}
+	if c.e.Namespace != "" {
+		name = c.e.Namespace + "_" + name
+	}
+	metric, ok := c.metrics[name]
+	if !ok {
+		metric = &MetricT{
+			Name:    name,
+			Help:    help,
+			Type:    metricType,
+			Samples: []SampleT{},
+		}
+		c.metrics[name] = metric
+		c.order = append(c.order, name)
+	}
+	metric.Samples = append(metric.Samples, SampleT{Labels: labels, Value: value})
+}
+
+// selected returns true if the metric matches Include and Exclude
+func (e *ExporterT) selected(name string) bool {
+	for _, prefix := range e.Exclude {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	if len(e.Include) == 0 {
+		return true
+	}
+	for _, prefix := range e.Include {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect reads the sources and returns the metrics
+// Failing sources don't stop the collection, they are reported using the exporter_scrape_error metric
+func (e *ExporterT) Collect() []MetricT {
+	start := time.Now()
+	c := e.newCollection()
+
+	sources := e.Sources
+	if len(sources) == 0 {
+		sources = AllSources
+	}
+	up := 0.0
+	for _, source := range sources {
+		var err error
+		switch source {
+		case SourceStat:
+			err = c.collectStat()
+		case SourceInfo:
+			err = c.collectInfo()
+		case SourcePools:
+			err = c.collectPools()
+		case SourceResolvers:
+			err = c.collectResolvers()
+		case SourceTables:
+			err = c.collectTables()
+		default:
+			continue
+		}
+		// haproxy is up if at least one source reached it, errors other than connection errors come from haproxy
+		var opErr *net.OpError
+		if err == nil || !errors.As(err, &opErr) {
+			up = 1
+		}
+		scrapeErr := 0.0
+		if err != nil {
+			scrapeErr = 1
+		}
+		c.add("exporter_scrape_error", "1 if collecting the source failed", Gauge, map[string]string{"source": source}, scrapeErr)
+	}
+	c.add("up", "1 if haproxy could be reached", Gauge, nil, up)
+	c.add("exporter_scrape_duration_seconds", "The time it took to collect the metrics", Gauge, nil, time.Since(start).Seconds())
+
+	return c.list()
+}
+
+// ServeHTTP serves the metrics in the prometheus text format
+func (e *ExporterT) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteMetrics(w, e.Collect())
+}
+
+// WriteMetrics writes metrics in the prometheus text format
+func WriteMetrics(w io.Writer, metrics []MetricT) error {
+	buf := bufio.NewWriter(w)
+	for _, metric := range metrics {
+		buf.WriteString("# HELP " + metric.Name + " " + helpReplacer.Replace(metric.Help) + "\n")
+		buf.WriteString("# TYPE " + metric.Name + " " + metric.Type + "\n")
+		for _, sample := range metric.Samples {
+			buf.WriteString(metric.Name)
+			if len(sample.Labels) > 0 {
+				keys := []string{}
+				for key := range sample.Labels {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				buf.WriteString("{")
+				for i, key := range keys {
+					if i > 0 {
+						buf.WriteString(",")
+					}
+					buf.WriteString(key + "=\"" + labelReplacer.Replace(sample.Labels[key]) + "\"")
+				}
+				buf.WriteString("}")
+			}
+			buf.WriteString(" " + strconv.FormatFloat(sample.Value, 'f', -1, 64) + "\n")
+		}
+	}
+	return buf.Flush()
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
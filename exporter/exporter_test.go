@@ -0,0 +1,255 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// fakeHaproxy listens on a unix socket and answers every command with the canned output, like haproxy it closes
+// the connection after a single command
+func fakeHaproxy(t *testing.T, responses map[string]string) *haproxysocket.HaproxyInstace {
+	address := filepath.Join(t.TempDir(), "haproxy.sock")
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			query, _ := bufio.NewReader(conn).ReadString('\n')
+			out, ok := responses[strings.TrimSpace(query)]
+			if !ok {
+				out = "Unknown command: '" + strings.TrimSpace(query) + "'\n"
+			}
+			conn.Write([]byte(out))
+			conn.Close()
+		}
+	}()
+
+	return haproxysocket.New("unix", address)
+}
+
+const testStat = "# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,status,type,\n" +
+	"fe_http,FRONTEND,,,3,10,2000,120,5000,9000,0,OPEN,0,\n" +
+	"be_app,app1,0,0,1,4,,60,2500,4500,,UP 1/3,2,\n" +
+	"be_app,BACKEND,0,0,1,4,200,60,2500,4500,0,UP,1,\n"
+
+const testInfo = "Name: HAProxy\n" +
+	"Version: 2.8.3\n" +
+	"Pid: 1\n" +
+	"Uptime_sec: 3600\n" +
+	"CurrConns: 4\n" +
+	"CumConns: 1200\n" +
+	"node: lb1\n"
+
+func TestWriteMetrics(t *testing.T) {
+	metrics := []MetricT{
+		{
+			Name: "haproxy_server_status",
+			Help: "Help with a \\ and a\nnewline",
+			Type: Gauge,
+			Samples: []SampleT{
+				{Labels: map[string]string{"proxy": "be_app", "server": `quote" backslash\ newline` + "\n"}, Value: 1},
+				{Labels: map[string]string{"server": "app2", "proxy": "be_app"}, Value: 0.5},
+			},
+		},
+		{
+			Name:    "haproxy_up",
+			Help:    "1 if haproxy could be reached",
+			Type:    Gauge,
+			Samples: []SampleT{{Value: 1}},
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := WriteMetrics(buf, metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP haproxy_server_status Help with a \\ and a\nnewline
+# TYPE haproxy_server_status gauge
+haproxy_server_status{proxy="be_app",server="quote\" backslash\\ newline\n"} 1
+haproxy_server_status{proxy="be_app",server="app2"} 0.5
+# HELP haproxy_up 1 if haproxy could be reached
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	if buf.String() != expected {
+		t.Errorf("got\n%v\nexpected\n%v", buf.String(), expected)
+	}
+}
+
+// scrape collects the metrics and returns them in the prometheus text format
+func scrape(t *testing.T, e *ExporterT) string {
+	buf := bytes.NewBuffer(nil)
+	err := WriteMetrics(buf, e.Collect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCollect(t *testing.T) {
+	e := New(fakeHaproxy(t, map[string]string{
+		"show stat": testStat,
+		"show info": testInfo,
+	}))
+	e.Sources = []string{SourceStat, SourceInfo}
+	out := scrape(t, e)
+
+	expectedLines := []string{
+		// Counters get the _total suffix and the counter type
+		"# TYPE haproxy_frontend_stot_total counter",
+		`haproxy_frontend_stot_total{proxy="fe_http"} 120`,
+		`haproxy_server_bin_total{proxy="be_app",server="app1"} 2500`,
+		"# TYPE haproxy_process_cum_conns_total counter",
+		"haproxy_process_cum_conns_total 1200",
+		// Gauges don't
+		"# TYPE haproxy_frontend_scur gauge",
+		`haproxy_frontend_scur{proxy="fe_http"} 3`,
+		`haproxy_backend_slim{proxy="be_app"} 200`,
+		"# TYPE haproxy_process_curr_conns gauge",
+		"haproxy_process_curr_conns 4",
+		// The transitional state "UP 1/3" is reported as UP
+		`haproxy_server_status{proxy="be_app",server="app1",state="UP"} 1`,
+		`haproxy_process_info{node="lb1",version="2.8.3"} 1`,
+		`haproxy_exporter_scrape_error{source="stat"} 0`,
+		`haproxy_exporter_scrape_error{source="info"} 0`,
+		"haproxy_up 1",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("expected line %q in\n%v", line, out)
+		}
+	}
+
+	unexpected := []string{
+		"haproxy_frontend_stot ",
+		"haproxy_frontend_scur_total",
+		"haproxy_process_pid",
+		"haproxy_frontend_pxname",
+	}
+	for _, part := range unexpected {
+		if strings.Contains(out, part) {
+			t.Errorf("didn't expect %q in\n%v", part, out)
+		}
+	}
+}
+
+func TestIncludeExclude(t *testing.T) {
+	tests := []struct {
+		name       string
+		include    []string
+		exclude    []string
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:     "everything",
+			expected: []string{"haproxy_frontend_scur", "haproxy_server_bin_total", "haproxy_process_uptime_sec", "haproxy_up"},
+		},
+		{
+			name:       "include",
+			include:    []string{"frontend_", "up"},
+			expected:   []string{"haproxy_frontend_scur", "haproxy_frontend_stot_total", "haproxy_up"},
+			unexpected: []string{"haproxy_server_bin_total", "haproxy_process_uptime_sec", "haproxy_exporter_scrape_error"},
+		},
+		{
+			name:       "exclude",
+			exclude:    []string{"server_", "process_"},
+			expected:   []string{"haproxy_frontend_scur", "haproxy_backend_scur", "haproxy_up"},
+			unexpected: []string{"haproxy_server_bin_total", "haproxy_process_uptime_sec", "haproxy_process_info"},
+		},
+		{
+			name:       "exclude wins from include",
+			include:    []string{"frontend_"},
+			exclude:    []string{"frontend_stot"},
+			expected:   []string{"haproxy_frontend_scur"},
+			unexpected: []string{"haproxy_frontend_stot_total", "haproxy_up"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := New(fakeHaproxy(t, map[string]string{
+				"show stat": testStat,
+				"show info": testInfo,
+			}))
+			e.Sources = []string{SourceStat, SourceInfo}
+			e.Include = test.include
+			e.Exclude = test.exclude
+
+			names := map[string]bool{}
+			for _, metric := range e.Collect() {
+				names[metric.Name] = true
+			}
+			for _, name := range test.expected {
+				if !names[name] {
+					t.Errorf("expected metric %v, got %v", name, names)
+				}
+			}
+			for _, name := range test.unexpected {
+				if names[name] {
+					t.Errorf("didn't expect metric %v", name)
+				}
+			}
+		})
+	}
+}
+
+func TestUp(t *testing.T) {
+	t.Run("dial fails", func(t *testing.T) {
+		e := New(haproxysocket.New("unix", filepath.Join(t.TempDir(), "missing.sock")))
+		out := scrape(t, e)
+		if !strings.Contains(out, "\nhaproxy_up 0\n") {
+			t.Errorf("expected haproxy_up 0 in\n%v", out)
+		}
+		for _, source := range AllSources {
+			line := `haproxy_exporter_scrape_error{source="` + source + `"} 1`
+			if !strings.Contains(out, line+"\n") {
+				t.Errorf("expected line %q in\n%v", line, out)
+			}
+		}
+	})
+
+	t.Run("source fails on haproxy", func(t *testing.T) {
+		// "show pools" is unknown to the fake haproxy, it is still reachable
+		e := New(fakeHaproxy(t, map[string]string{}))
+		e.Sources = []string{SourcePools}
+		out := scrape(t, e)
+		if !strings.Contains(out, "\nhaproxy_up 1\n") {
+			t.Errorf("expected haproxy_up 1 in\n%v", out)
+		}
+		if !strings.Contains(out, `haproxy_exporter_scrape_error{source="pools"} 1`+"\n") {
+			t.Errorf("expected a pools scrape error in\n%v", out)
+		}
+	})
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"CumSslConns":        "cum_ssl_conns",
+		"PoolAlloc_MB":       "pool_alloc_mb",
+		"Uptime_sec":         "uptime_sec",
+		"ConnRate":           "conn_rate",
+		"Tasks":              "tasks",
+		"CurrSslConns":       "curr_ssl_conns",
+		"SslFrontendKeyRate": "ssl_frontend_key_rate",
+	}
+	for in, expected := range tests {
+		if got := snakeCase(in); got != expected {
+			t.Errorf("snakeCase(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+}
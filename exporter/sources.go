@@ -0,0 +1,229 @@
+package exporter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mjarkk/haproxysocket"
+)
+
+// The "show stat" fields that only go up, all other numeric fields are gauges
+var statCounters = map[string]bool{}
+
+func init() {
+	for _, counter := range haproxysocket.StatCounters {
+		statCounters[counter] = true
+	}
+}
+
+// The "show stat" fields that are not exported as metric
+var statSkip = map[string]bool{
+	"pxname": true,
+	"svname": true,
+	"pid":    true,
+	"iid":    true,
+	"sid":    true,
+	"type":   true,
+	"status": true, // Exported as <kind>_status with a state label
+}
+
+// The "show stat" type column
+var statKinds = map[string]string{
+	"0": "frontend",
+	"1": "backend",
+	"2": "server",
+	"3": "listener",
+}
+
+func (c *collection) collectStat() error {
+	stats, err := c.e.h.ShowStat()
+	if err != nil {
+		return err
+	}
+	for _, row := range stats {
+		kind, ok := statKinds[row["type"]]
+		if !ok {
+			continue
+		}
+		labels := map[string]string{"proxy": row["pxname"]}
+		if kind == "server" || kind == "listener" {
+			labels[kind] = row["svname"]
+		}
+
+		if status := row["status"]; status != "" {
+			// Values like "UP 1/3" are transitional states
+			stateLabels := copyLabels(labels)
+			stateLabels["state"] = strings.Fields(status)[0]
+			c.add(kind+"_status", "The status of the "+kind+", the state label contains the current state", Gauge, stateLabels, 1)
+		}
+
+		for _, field := range sortedKeys(row) {
+			value := row[field]
+			if statSkip[field] || value == "" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			if statCounters[field] {
+				c.add(kind+"_"+field+"_total", "The \"show stat\" counter "+field, Counter, labels, parsed)
+				continue
+			}
+			c.add(kind+"_"+field, "The \"show stat\" value "+field, Gauge, labels, parsed)
+		}
+	}
+	return nil
+}
+
+// The "show info" fields that only go up next to the fields starting with Cum or Total
+var infoCounters = map[string]bool{
+	"DroppedLogs":       true,
+	"FailedResolutions": true,
+}
+
+func (c *collection) collectInfo() error {
+	info, err := c.e.h.ShowInfo()
+	if err != nil {
+		return err
+	}
+	c.add("process_info", "Information about the haproxy process", Gauge, map[string]string{
+		"version": info["Version"],
+		"node":    info["node"],
+	}, 1)
+
+	for _, field := range sortedKeys(info) {
+		value := info[field]
+		if field == "Pid" || field == "Process_num" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		name := "process_" + snakeCase(field)
+		if infoCounters[field] || strings.HasPrefix(field, "Cum") || strings.HasPrefix(field, "Total") {
+			c.add(name+"_total", "The \"show info\" counter "+field, Counter, nil, parsed)
+			continue
+		}
+		c.add(name, "The \"show info\" value "+field, Gauge, nil, parsed)
+	}
+	return nil
+}
+
+func (c *collection) collectPools() error {
+	pools, err := c.e.h.ShowPools()
+	if err != nil {
+		return err
+	}
+	// Pools with the same name are combined
+	order := []string{}
+	used := map[string]float64{}
+	users := map[string]float64{}
+	failures := map[string]float64{}
+	for _, pool := range pools {
+		if _, ok := used[pool.Name]; !ok {
+			order = append(order, pool.Name)
+		}
+		used[pool.Name] += float64(pool.Used)
+		users[pool.Name] += float64(pool.Users)
+		failures[pool.Name] += float64(pool.Failures)
+	}
+	for _, name := range order {
+		labels := map[string]string{"pool": name}
+		c.add("pool_used", "The amount of used entries in the pool", Gauge, labels, used[name])
+		c.add("pool_users", "The amount of users of the pool", Gauge, labels, users[name])
+		c.add("pool_failures_total", "The amount of failed allocations", Counter, labels, failures[name])
+	}
+	return nil
+}
+
+// The "show stat resolvers" fields that are not counters, like the outstanding requests
+var resolverGauges = []string{"outstanding", "que_len", "queue_len"}
+
+func (c *collection) collectResolvers() error {
+	nameservers, err := c.e.h.ShowStatResolvers()
+	if err != nil {
+		return err
+	}
+	for _, nameserver := range nameservers {
+		labels := map[string]string{
+			"resolvers":  nameserver["resolvers"],
+			"nameserver": nameserver["nameserver"],
+		}
+		for _, field := range sortedKeys(nameserver) {
+			value := nameserver[field]
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			if inList(field, resolverGauges) {
+				c.add("resolver_"+snakeCase(field), "The \"show stat resolvers\" value "+field, Gauge, labels, parsed)
+				continue
+			}
+			c.add("resolver_"+snakeCase(field)+"_total", "The \"show stat resolvers\" counter "+field, Counter, labels, parsed)
+		}
+	}
+	return nil
+}
+
+func (c *collection) collectTables() error {
+	tables, err := c.e.h.ShowTables()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		labels := map[string]string{"table": table.Name, "type": table.Type}
+		c.add("sticktable_size", "The max amount of entries in the stick table", Gauge, labels, float64(table.Size))
+		c.add("sticktable_used", "The amount of used entries in the stick table", Gauge, labels, float64(table.Used))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	toReturn := []string{}
+	for key := range m {
+		toReturn = append(toReturn, key)
+	}
+	sort.Strings(toReturn)
+	return toReturn
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	toReturn := map[string]string{}
+	for key, value := range labels {
+		toReturn[key] = value
+	}
+	return toReturn
+}
+
+// snakeCase converts names like CumSslConns and PoolAlloc_MB to cum_ssl_conns and pool_alloc_mb
+func snakeCase(in string) string {
+	out := []rune{}
+	var last rune
+	for _, r := range in {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			r = '_'
+		}
+		if unicode.IsUpper(r) && (unicode.IsLower(last) || unicode.IsDigit(last)) {
+			out = append(out, '_')
+		}
+		if r == '_' && last == '_' {
+			continue
+		}
+		out = append(out, unicode.ToLower(r))
+		last = r
+	}
+	return strings.Trim(string(out), "_")
+}
+
+func inList(item string, list []string) bool {
+	for _, listItem := range list {
+		if item == listItem {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,16 @@ package haproxysocket
 type HaproxyInstace struct {
 	Network string
 	Address string
+
+	// Audit is called after every command that changes haproxy, see AuditWriterT for a ready to use hook
+	Audit func(entry AuditEntryT)
+	Actor string // Recorded in the audit entries, see As
+
+	// Interceptors wrap every command sent to haproxy, for example for logging, metrics or policy checks
+	Interceptors []InterceptorT
+
+	// prefix is added in front of every command, used by the master cli to route commands to a worker
+	prefix string
 }
 
 // New creates a new haproxyInstace instace
@@ -0,0 +1,375 @@
+package haproxysocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryStatFields are the "show stat" fields kept by the history if HistoryT.StatFields is empty
+var DefaultHistoryStatFields = []string{
+	"scur", "qcur", "rate", "req_rate", "weight", "act", "bck",
+	"stot", "bin", "bout", "req_tot", "hrsp_4xx", "hrsp_5xx", "ereq", "econ", "eresp",
+	"qtime", "ctime", "rtime", "ttime",
+}
+
+// DefaultHistoryInfoFields are the "show info" fields kept by the history if HistoryT.InfoFields is empty
+var DefaultHistoryInfoFields = []string{
+	"CurrConns", "ConnRate", "SessRate", "SslRate", "Run_queue", "Idle_pct", "Tasks",
+}
+
+// DefaultHistoryTiers are used if no tiers are given to History, together they keep about 3 days when sampling every 10 seconds
+var DefaultHistoryTiers = []HistoryTierT{
+	{Resolution: 0, Size: 360},
+	{Resolution: time.Minute, Size: 360},
+	{Resolution: 10 * time.Minute, Size: 432},
+}
+
+// HistoryTierT is a ring buffer with a fixed resolution
+type HistoryTierT struct {
+	Resolution time.Duration // The duration of 1 point, 0 keeps every sample
+	Size       int           // The max amount of points, the oldest points are dropped
+}
+
+// HistoryKeyT identifies a series, for "show info" fields Proxy and Server are empty
+type HistoryKeyT struct {
+	Proxy  string `json:"proxy"`
+	Server string `json:"server"`
+	Field  string `json:"field"`
+}
+
+// HistoryPointT is a single point of a series
+// For downsampled points Value is the average of Count samples
+type HistoryPointT struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"`
+}
+
+// historyRing is a fixed size ring buffer of points
+type historyRing struct {
+	resolution time.Duration
+	points     []HistoryPointT
+	start      int
+	size       int
+	pending    *HistoryPointT // The point that is still being filled for downsampled tiers
+}
+
+func (r *historyRing) push(p HistoryPointT) {
+	if len(r.points) < r.size {
+		r.points = append(r.points, p)
+		return
+	}
+	r.points[r.start] = p
+	r.start = (r.start + 1) % r.size
+}
+
+func (r *historyRing) add(p HistoryPointT) {
+	if r.resolution == 0 {
+		r.push(p)
+		return
+	}
+	bucket := p.Time.Truncate(r.resolution)
+	if r.pending != nil && !r.pending.Time.Equal(bucket) {
+		r.push(*r.pending)
+		r.pending = nil
+	}
+	if r.pending == nil {
+		r.pending = &HistoryPointT{Time: bucket, Value: p.Value, Min: p.Min, Max: p.Max, Count: p.Count}
+		return
+	}
+	r.pending.Value = (r.pending.Value*float64(r.pending.Count) + p.Value*float64(p.Count)) / float64(r.pending.Count+p.Count)
+	r.pending.Min = math.Min(r.pending.Min, p.Min)
+	r.pending.Max = math.Max(r.pending.Max, p.Max)
+	r.pending.Count += p.Count
+}
+
+// list returns all points from old to new, including the pending point
+func (r *historyRing) list() []HistoryPointT {
+	toReturn := make([]HistoryPointT, 0, len(r.points)+1)
+	toReturn = append(toReturn, r.points[r.start:]...)
+	toReturn = append(toReturn, r.points[:r.start]...)
+	if r.pending != nil {
+		toReturn = append(toReturn, *r.pending)
+	}
+	return toReturn
+}
+
+// HistoryT keeps the sampled stats in memory, create one using History
+type HistoryT struct {
+	h      *HaproxyInstace
+	m      sync.RWMutex
+	poller *StatPollerT
+
+	Interval   time.Duration // The time between samples when using Run, defaults to 10 seconds
+	StatFields []string      // The "show stat" fields to keep, defaults to DefaultHistoryStatFields
+	InfoFields []string      // The "show info" fields to keep, defaults to DefaultHistoryInfoFields
+	// Rates stores the counters (see StatCounters) as per second rates instead of the cumulative values
+	Rates bool
+	// Expire removes the series of proxies and servers that are not in "show stat" for this duration, defaults to 10 minutes
+	Expire time.Duration
+
+	tiers    []HistoryTierT
+	series   map[HistoryKeyT][]*historyRing
+	lastSeen map[HistoryKeyT]time.Time
+}
+
+// History creates an in-memory stats history, tiers are the ring buffers of every series and default to DefaultHistoryTiers
+// Every tier needs a Size above 0 and a Resolution of 0 or more
+func (h *HaproxyInstace) History(tiers ...HistoryTierT) (*HistoryT, error) {
+	if len(tiers) == 0 {
+		tiers = DefaultHistoryTiers
+	}
+	for i, tier := range tiers {
+		if tier.Size <= 0 {
+			return nil, fmt.Errorf("tier %v has a size of %v, must be more than 0", i, tier.Size)
+		}
+		if tier.Resolution < 0 {
+			return nil, fmt.Errorf("tier %v has a negative resolution", i)
+		}
+	}
+	return &HistoryT{
+		h:        h,
+		poller:   h.StatPoller(),
+		Rates:    true,
+		tiers:    append([]HistoryTierT{}, tiers...),
+		series:   map[HistoryKeyT][]*historyRing{},
+		lastSeen: map[HistoryKeyT]time.Time{},
+	}, nil
+}
+
+// Tiers returns the ring buffers of every series
+func (hist *HistoryT) Tiers() []HistoryTierT {
+	return append([]HistoryTierT{}, hist.tiers...)
+}
+
+// Sample takes a sample and adds it to the history
+func (hist *HistoryT) Sample() error {
+	snapshot := hist.poller.Poll()
+	if snapshot.Err != nil {
+		return snapshot.Err
+	}
+	hist.Add(snapshot)
+	return nil
+}
+
+// Add adds a snapshot from a StatPollerT to the history, failed snapshots are ignored
+// The series of proxies and servers that are not in the snapshots for longer than Expire are removed
+func (hist *HistoryT) Add(snapshot StatSnapshotT) {
+	if snapshot.Err != nil {
+		return
+	}
+	statFields := hist.StatFields
+	if len(statFields) == 0 {
+		statFields = DefaultHistoryStatFields
+	}
+	infoFields := hist.InfoFields
+	if len(infoFields) == 0 {
+		infoFields = DefaultHistoryInfoFields
+	}
+
+	hist.m.Lock()
+	defer hist.m.Unlock()
+
+	for _, row := range snapshot.Rows {
+		for _, field := range statFields {
+			key := HistoryKeyT{Proxy: row.Proxy, Server: row.Server, Field: field}
+			if hist.Rates && inList(field, StatCounters) {
+				rate, ok := row.Rates[field]
+				if ok {
+					hist.add(key, snapshot.Time, rate)
+				}
+				continue
+			}
+			value, err := strconv.ParseFloat(row.Values[field], 64)
+			if err == nil {
+				hist.add(key, snapshot.Time, value)
+			}
+		}
+	}
+	for _, field := range infoFields {
+		value, err := strconv.ParseFloat(snapshot.Info[field], 64)
+		if err == nil {
+			hist.add(HistoryKeyT{Field: field}, snapshot.Time, value)
+		}
+	}
+
+	expire := hist.Expire
+	if expire == 0 {
+		expire = 10 * time.Minute
+	}
+	for key, lastSeen := range hist.lastSeen {
+		// The "show info" series always exist
+		if key.Proxy != "" && snapshot.Time.Sub(lastSeen) > expire {
+			delete(hist.series, key)
+			delete(hist.lastSeen, key)
+		}
+	}
+}
+
+func (hist *HistoryT) add(key HistoryKeyT, t time.Time, value float64) {
+	hist.lastSeen[key] = t
+	rings, ok := hist.series[key]
+	if !ok {
+		for _, tier := range hist.tiers {
+			rings = append(rings, &historyRing{resolution: tier.Resolution, size: tier.Size})
+		}
+		hist.series[key] = rings
+	}
+	for _, ring := range rings {
+		ring.add(HistoryPointT{Time: t, Value: value, Min: value, Max: value, Count: 1})
+	}
+}
+
+// Run samples every Interval until ctx is done
+// onError is optional and is called when a sample failed
+func (hist *HistoryT) Run(ctx context.Context, onError func(err error)) {
+	interval := hist.Interval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	for {
+		err := hist.Sample()
+		if err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Keys returns all series in the history
+func (hist *HistoryT) Keys() []HistoryKeyT {
+	hist.m.RLock()
+	defer hist.m.RUnlock()
+	toReturn := []HistoryKeyT{}
+	for key := range hist.series {
+		toReturn = append(toReturn, key)
+	}
+	sort.Slice(toReturn, func(i, j int) bool {
+		a, b := toReturn[i], toReturn[j]
+		if a.Proxy != b.Proxy {
+			return a.Proxy < b.Proxy
+		}
+		if a.Server != b.Server {
+			return a.Server < b.Server
+		}
+		return a.Field < b.Field
+	})
+	return toReturn
+}
+
+// HistoryQueryT selects a series and time window
+type HistoryQueryT struct {
+	HistoryKeyT
+	From time.Time     // Defaults to the oldest point
+	To   time.Time     // Defaults to now
+	Step time.Duration // Optional, combine the points into buckets of this duration
+}
+
+// HistorySeriesT is the result of a query
+type HistorySeriesT struct {
+	Key        HistoryKeyT     `json:"key"`
+	Resolution time.Duration   `json:"resolution"` // The resolution of the tier the points came from
+	Points     []HistoryPointT `json:"points"`
+	Min        float64         `json:"min"`
+	Max        float64         `json:"max"`
+	Avg        float64         `json:"avg"`
+	Count      int             `json:"count"` // The amount of samples within the window
+}
+
+// Query returns the points of a series within the window
+// The points come from the most detailed tier that covers the whole window
+func (hist *HistoryT) Query(q HistoryQueryT) (HistorySeriesT, error) {
+	toReturn := HistorySeriesT{
+		Key:    q.HistoryKeyT,
+		Points: []HistoryPointT{},
+	}
+	if q.To.IsZero() {
+		q.To = time.Now()
+	}
+
+	hist.m.RLock()
+	rings, ok := hist.series[q.HistoryKeyT]
+	if !ok {
+		hist.m.RUnlock()
+		return toReturn, errors.New("unknown series " + q.Proxy + "/" + q.Server + "/" + q.Field)
+	}
+	var points []HistoryPointT
+	for i, ring := range rings {
+		points = ring.list()
+		toReturn.Resolution = ring.resolution
+		if len(points) > 0 && !points[0].Time.After(q.From) {
+			break
+		}
+		if i < len(rings)-1 && len(points) < ring.size {
+			// This tier isn't full yet so the next tiers don't have older data
+			break
+		}
+	}
+	hist.m.RUnlock()
+
+	for _, point := range points {
+		if point.Time.Before(q.From) || point.Time.After(q.To) {
+			continue
+		}
+		if q.Step > 0 {
+			bucket := point.Time.Truncate(q.Step)
+			last := len(toReturn.Points) - 1
+			if last >= 0 && toReturn.Points[last].Time.Equal(bucket) {
+				p := &toReturn.Points[last]
+				p.Value = (p.Value*float64(p.Count) + point.Value*float64(point.Count)) / float64(p.Count+point.Count)
+				p.Min = math.Min(p.Min, point.Min)
+				p.Max = math.Max(p.Max, point.Max)
+				p.Count += point.Count
+				continue
+			}
+			point.Time = bucket
+		}
+		toReturn.Points = append(toReturn.Points, point)
+	}
+
+	sum := 0.0
+	for i, point := range toReturn.Points {
+		if i == 0 || point.Min < toReturn.Min {
+			toReturn.Min = point.Min
+		}
+		if i == 0 || point.Max > toReturn.Max {
+			toReturn.Max = point.Max
+		}
+		sum += point.Value * float64(point.Count)
+		toReturn.Count += point.Count
+	}
+	if toReturn.Count > 0 {
+		toReturn.Avg = sum / float64(toReturn.Count)
+	}
+	return toReturn, nil
+}
+
+// Percentile returns the p-th percentile (0 - 100) of the point values using linear interpolation
+// For downsampled points the averages are used
+func (s HistorySeriesT) Percentile(p float64) float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	values := make([]float64, len(s.Points))
+	for i, point := range s.Points {
+		values[i] = point.Value
+	}
+	sort.Float64s(values)
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,105 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryTiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		tiers     []HistoryTierT
+		expectErr bool
+	}{
+		{name: "default"},
+		{name: "valid", tiers: []HistoryTierT{{Size: 10}, {Resolution: time.Minute, Size: 60}}},
+		{name: "zero size", tiers: []HistoryTierT{{Size: 10}, {Resolution: time.Minute}}, expectErr: true},
+		{name: "negative resolution", tiers: []HistoryTierT{{Resolution: -time.Second, Size: 10}}, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hist, err := New("unix", "/nonexistent.sock").History(test.tiers...)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := test.tiers
+			if len(expected) == 0 {
+				expected = DefaultHistoryTiers
+			}
+			if !reflect.DeepEqual(hist.Tiers(), expected) {
+				t.Errorf("got tiers %v, expected %v", hist.Tiers(), expected)
+			}
+		})
+	}
+}
+
+// historySnapshot creates a snapshot with the scur value of every server
+func historySnapshot(at time.Time, servers map[string]string) StatSnapshotT {
+	snapshot := StatSnapshotT{
+		Time: at,
+		Info: map[string]string{"CurrConns": "1"},
+	}
+	for server, scur := range servers {
+		snapshot.Rows = append(snapshot.Rows, StatRowT{
+			Proxy:  "be_app",
+			Server: server,
+			Values: map[string]string{"scur": scur},
+		})
+	}
+	return snapshot
+}
+
+func TestHistoryExpire(t *testing.T) {
+	hist, err := New("unix", "/nonexistent.sock").History(HistoryTierT{Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist.StatFields = []string{"scur"}
+	hist.InfoFields = []string{"CurrConns"}
+	hist.Expire = time.Minute
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	hist.Add(historySnapshot(start, map[string]string{"app1": "1", "app2": "2"}))
+	// app2 is removed from haproxy
+	hist.Add(historySnapshot(start.Add(30*time.Second), map[string]string{"app1": "1"}))
+	// A failed poll doesn't count
+	hist.Add(StatSnapshotT{Time: start.Add(45 * time.Second), Err: ErrReadOnly})
+
+	keys := []HistoryKeyT{
+		{Field: "CurrConns"},
+		{Proxy: "be_app", Server: "app1", Field: "scur"},
+		{Proxy: "be_app", Server: "app2", Field: "scur"},
+	}
+	if !reflect.DeepEqual(hist.Keys(), keys) {
+		t.Fatalf("got keys %v, expected %v", hist.Keys(), keys)
+	}
+
+	hist.Add(historySnapshot(start.Add(90*time.Second), map[string]string{"app1": "3"}))
+	keys = []HistoryKeyT{
+		{Field: "CurrConns"},
+		{Proxy: "be_app", Server: "app1", Field: "scur"},
+	}
+	if !reflect.DeepEqual(hist.Keys(), keys) {
+		t.Fatalf("got keys %v, expected %v", hist.Keys(), keys)
+	}
+	_, err = hist.Query(HistoryQueryT{HistoryKeyT: HistoryKeyT{Proxy: "be_app", Server: "app2", Field: "scur"}})
+	if err == nil {
+		t.Error("expected the expired series to be unknown")
+	}
+
+	series, err := hist.Query(HistoryQueryT{HistoryKeyT: keys[1], To: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series.Count != 3 || series.Min != 1 || series.Max != 3 {
+		t.Errorf("got count %v, min %v and max %v, expected 3, 1 and 3", series.Count, series.Min, series.Max)
+	}
+}
@@ -0,0 +1,82 @@
+package haproxysocket
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// CommandT is a command that goes through the interceptors of an instance
+type CommandT struct {
+	Instance *HaproxyInstace
+	Query    string    // Can be changed by an interceptor before calling next
+	Start    time.Time // When the command was started
+	// Duration is the time spent executing the command on haproxy, it is set when next returns
+	// and stays zero if the command was short-circuited
+	Duration time.Duration
+}
+
+// InterceptorT wraps the execution of every command on an instance
+// It calls next to continue with the next interceptor and eventually haproxy and receives the raw response,
+// or it returns its own response without calling next to short-circuit the command
+type InterceptorT func(c *CommandT, next func() (string, error)) (string, error)
+
+// ErrReadOnly is returned by the ReadOnly interceptor
+var ErrReadOnly = errors.New("the instance is read only")
+
+// q executes a query through the interceptors
+// The first interceptor is the outermost one, the audit hook wraps all interceptors so every command
+// is recorded once, also when it is retried or short-circuited by an interceptor
+func (h *HaproxyInstace) q(query string) (string, error) {
+	if h.Audit != nil && IsMutating(query) {
+		return h.auditQ(query)
+	}
+	return h.intercept(query)
+}
+
+// intercept executes a query through the interceptors
+func (h *HaproxyInstace) intercept(query string) (string, error) {
+	c := &CommandT{
+		Instance: h,
+		Query:    query,
+		Start:    time.Now(),
+	}
+	return h.next(0, c)
+}
+
+func (h *HaproxyInstace) next(i int, c *CommandT) (string, error) {
+	if i >= len(h.Interceptors) {
+		start := time.Now()
+		out, err := h.exec(c.Query)
+		c.Duration = time.Since(start)
+		return out, err
+	}
+	return h.Interceptors[i](c, func() (string, error) {
+		return h.next(i+1, c)
+	})
+}
+
+// ReadOnly is an interceptor that rejects all commands that change haproxy with ErrReadOnly
+func ReadOnly(c *CommandT, next func() (string, error)) (string, error) {
+	if IsMutating(c.Query) {
+		return "", ErrReadOnly
+	}
+	return next()
+}
+
+// Retry returns an interceptor that retries a command when haproxy can't be reached
+// Only connection errors are retried, the command never reached haproxy so this is also safe for commands that change haproxy
+func Retry(attempts int, wait time.Duration) InterceptorT {
+	return func(c *CommandT, next func() (string, error)) (string, error) {
+		out, err := next()
+		for i := 1; i < attempts; i++ {
+			var opErr *net.OpError
+			if err == nil || !errors.As(err, &opErr) || opErr.Op != "dial" {
+				break
+			}
+			time.Sleep(wait)
+			out, err = next()
+		}
+		return out, err
+	}
+}
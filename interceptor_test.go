@@ -0,0 +1,215 @@
+package haproxysocket
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSocket is a haproxy socket that answers queries with canned output and records the queries it received
+type fakeSocket struct {
+	m       sync.Mutex
+	queries []string
+}
+
+func (f *fakeSocket) received() []string {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return append([]string{}, f.queries...)
+}
+
+func newFakeSocket(t *testing.T, responses map[string]string) (*HaproxyInstace, *fakeSocket) {
+	address := filepath.Join(t.TempDir(), "haproxy.sock")
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	f := &fakeSocket{queries: []string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			query, _ := bufio.NewReader(conn).ReadString('\n')
+			query = strings.TrimSpace(query)
+			f.m.Lock()
+			f.queries = append(f.queries, query)
+			f.m.Unlock()
+			out, ok := responses[query]
+			if !ok {
+				out = "Unknown command: '" + query + "'\n"
+			}
+			conn.Write([]byte(out))
+			conn.Close()
+		}
+	}()
+
+	return New("unix", address), f
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	h, socket := newFakeSocket(t, map[string]string{"show info": "Name: HAProxy"})
+
+	calls := []string{}
+	record := func(name string) InterceptorT {
+		return func(c *CommandT, next func() (string, error)) (string, error) {
+			calls = append(calls, name+" before")
+			out, err := next()
+			calls = append(calls, name+" after")
+			return out, err
+		}
+	}
+	rewrite := func(c *CommandT, next func() (string, error)) (string, error) {
+		c.Query = "show info"
+		return next()
+	}
+	h.Interceptors = []InterceptorT{record("first"), record("second"), rewrite, record("third")}
+
+	out, err := h.q("show version")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "Name: HAProxy" {
+		t.Errorf("got output %q", out)
+	}
+	expectedCalls := []string{"first before", "second before", "third before", "third after", "second after", "first after"}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("got calls %v, expected %v", calls, expectedCalls)
+	}
+	if queries := socket.received(); !reflect.DeepEqual(queries, []string{"show info"}) {
+		t.Errorf("socket received %v, expected the rewritten query", queries)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	h, socket := newFakeSocket(t, map[string]string{})
+
+	var command *CommandT
+	reachedSecond := false
+	h.Interceptors = []InterceptorT{
+		func(c *CommandT, next func() (string, error)) (string, error) {
+			command = c
+			return "cached", nil
+		},
+		func(c *CommandT, next func() (string, error)) (string, error) {
+			reachedSecond = true
+			return next()
+		},
+	}
+
+	out, err := h.q("show stat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "cached" {
+		t.Errorf("got output %q, expected the short-circuited output", out)
+	}
+	if reachedSecond {
+		t.Errorf("the second interceptor was called")
+	}
+	if queries := socket.received(); len(queries) != 0 {
+		t.Errorf("socket received %v, expected nothing", queries)
+	}
+	if command.Duration != 0 {
+		t.Errorf("got duration %v for a short-circuited command, expected 0", command.Duration)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		expectedErr     error
+		expectedQueries []string
+	}{
+		{
+			name:            "read",
+			query:           "show stat",
+			expectedQueries: []string{"show stat"},
+		},
+		{
+			name:            "write",
+			query:           "set server be_app/app1 state drain",
+			expectedErr:     ErrReadOnly,
+			expectedQueries: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, socket := newFakeSocket(t, map[string]string{"show stat": "# pxname,svname,"})
+			h.Interceptors = []InterceptorT{ReadOnly}
+			_, err := h.q(test.query)
+			if err != test.expectedErr {
+				t.Errorf("got error %v, expected %v", err, test.expectedErr)
+			}
+			if queries := socket.received(); !reflect.DeepEqual(queries, test.expectedQueries) {
+				t.Errorf("socket received %v, expected %v", queries, test.expectedQueries)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	h, _ := newFakeSocket(t, map[string]string{})
+	missing := New("unix", filepath.Join(t.TempDir(), "missing.sock"))
+
+	tests := []struct {
+		name             string
+		h                *HaproxyInstace
+		inner            InterceptorT // Placed between Retry and the socket
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{
+			name:             "dial error",
+			h:                missing,
+			expectedAttempts: 3,
+			expectErr:        true,
+		},
+		{
+			name:             "haproxy error output",
+			h:                h,
+			expectedAttempts: 1,
+		},
+		{
+			name: "other error",
+			h:    h,
+			inner: func(c *CommandT, next func() (string, error)) (string, error) {
+				return "", errors.New("not a connection error")
+			},
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			count := func(c *CommandT, next func() (string, error)) (string, error) {
+				attempts++
+				return next()
+			}
+			instance := *test.h
+			instance.Interceptors = []InterceptorT{Retry(3, 0), count}
+			if test.inner != nil {
+				instance.Interceptors = append(instance.Interceptors, test.inner)
+			}
+			_, err := instance.q("show info")
+			if (err != nil) != test.expectErr {
+				t.Errorf("got error %v, expected error: %v", err, test.expectErr)
+			}
+			if attempts != test.expectedAttempts {
+				t.Errorf("got %v attempts, expected %v", attempts, test.expectedAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package haproxysocket
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MasterT is a connection to the master cli of haproxy running in master-worker mode
+// In your haproxy config start haproxy with -W -S /var/run/haproxy-master.sock
+type MasterT struct {
+	h *HaproxyInstace
+}
+
+// NewMaster creates a new master cli instance
+func NewMaster(network, address string) *MasterT {
+	return &MasterT{
+		h: New(network, address),
+	}
+}
+
+// Master uses the instance as master cli, the instance must be connected to the master socket
+// The Audit hook, Actor and Interceptors of the instance are also used for the workers
+func (h *HaproxyInstace) Master() *MasterT {
+	return &MasterT{
+		h: h,
+	}
+}
+
+// ProcT is a process from "show proc"
+type ProcT struct {
+	PID         int           `json:"pid"`
+	Type        string        `json:"type"`        // master, worker or the name of a program
+	RelativePID int           `json:"relativePid"` // The relative pid used for @<relative pid>, 0 if unknown
+	Reloads     int           `json:"reloads"`
+	Failed      int           `json:"failed"` // The amount of failed reloads, only set for the master
+	Uptime      time.Duration `json:"uptime"`
+	Version     string        `json:"version"`
+	Old         bool          `json:"old"` // This is an old worker that is still finishing its connections after a reload
+	Program     bool          `json:"program"`
+}
+
+var procHeaderRegex = regexp.MustCompile(`<[^>]+>`)
+var procValueRegex = regexp.MustCompile(`\[[^\]]*\]|\S+`)
+
+// ShowProc list the master, workers, old workers and programs
+func (m *MasterT) ShowProc() ([]ProcT, error) {
+	toReturn := []ProcT{}
+	out, err := m.h.q("show proc")
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "#") {
+		return toReturn, errors.New(out)
+	}
+
+	// The output looks like:
+	// #<PID>          <type>          <reloads>       <uptime>        <version>
+	// 1162            master          5 [failed: 0]   0d00h02m07s     2.4.0
+	// # workers
+	// 1271            worker          1               0d00h00m00s     2.4.0
+	// # old workers
+	// 1233            worker          3               0d00h00m43s     2.4.0
+	// # programs
+	// 1244            dataplane-api   1               0d00h00m00s     -
+	lines := strings.Split(out, "\n")
+	columns := procHeaderRegex.FindAllString(lines[0], -1)
+	section := ""
+	workerIndex := 0
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			continue
+		}
+
+		// Values like "[failed: 0]" belong to the previous value
+		values := []string{}
+		for _, value := range procValueRegex.FindAllString(line, -1) {
+			if strings.HasPrefix(value, "[failed") && len(values) > 0 {
+				values[len(values)-1] = values[len(values)-1] + " " + value
+				continue
+			}
+			values = append(values, value)
+		}
+
+		proc := ProcT{
+			Old:     section == "old workers",
+			Program: section == "programs",
+		}
+		for i, column := range columns {
+			if i >= len(values) {
+				break
+			}
+			value := values[i]
+			switch strings.ToLower(strings.Trim(column, "<>")) {
+			case "pid":
+				proc.PID, _ = strconv.Atoi(value)
+			case "type":
+				proc.Type = value
+			case "relative pid":
+				// Old workers show "[was: 1]"
+				value = strings.TrimSpace(strings.TrimPrefix(strings.Trim(value, "[]"), "was:"))
+				proc.RelativePID, _ = strconv.Atoi(value)
+			case "reloads":
+				parts := strings.SplitN(value, " ", 2)
+				proc.Reloads, _ = strconv.Atoi(parts[0])
+				if len(parts) == 2 {
+					failed := strings.TrimSpace(strings.TrimPrefix(strings.Trim(parts[1], "[]"), "failed:"))
+					proc.Failed, _ = strconv.Atoi(failed)
+				}
+			case "uptime":
+				proc.Uptime = parseDuration(value)
+			case "version":
+				proc.Version = value
+			}
+		}
+
+		// Newer haproxy versions don't show the relative pid, it's the position in the workers list
+		if section == "workers" {
+			workerIndex++
+			if proc.RelativePID == 0 {
+				proc.RelativePID = workerIndex
+			}
+		}
+		toReturn = append(toReturn, proc)
+	}
+
+	return toReturn, nil
+}
+
+// ReloadResultT is the result of Reload
+type ReloadResultT struct {
+	Known       bool   `json:"known"`   // Older haproxy versions don't report the result of a reload, then this is false
+	Success     bool   `json:"success"` // Only valid if Known is true
+	StartupLogs string `json:"startupLogs"`
+}
+
+// Reload reloads haproxy and returns the startup logs of the new worker
+// Since haproxy 2.7 the result is reported directly, for older versions Known is false and StartupLogs is empty,
+// the master re-executes itself so the startup logs must be read later using ShowStartupLogs
+func (m *MasterT) Reload() (ReloadResultT, error) {
+	toReturn := ReloadResultT{}
+	out, err := m.h.q("reload")
+	if err != nil {
+		return toReturn, err
+	}
+
+	// The output looks like:
+	// Success=1
+	// --
+	// [NOTICE]   (1) : haproxy version is 2.7.0
+	if strings.HasPrefix(out, "Success=") {
+		parts := strings.SplitN(out, "\n", 2)
+		toReturn.Known = true
+		toReturn.Success = strings.TrimSpace(parts[0]) == "Success=1"
+		if len(parts) == 2 {
+			toReturn.StartupLogs = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(parts[1]), "--"))
+		}
+		return toReturn, nil
+	}
+	if out != "" && !strings.HasPrefix(out, "[") {
+		return toReturn, errors.New(out)
+	}
+	return toReturn, nil
+}
+
+// ShowStartupLogs report the logs of the last startup or reload
+func (m *MasterT) ShowStartupLogs() (string, error) {
+	return m.h.q("show startup-logs")
+}
+
+// Master returns an instance that executes commands on the master process itself (@master)
+func (m *MasterT) Master() *HaproxyInstace {
+	return m.route("@master")
+}
+
+// Worker returns an instance that routes every command to the worker with this relative pid (@<relative pid>)
+func (m *MasterT) Worker(relativePID int) *HaproxyInstace {
+	return m.route("@" + strconv.Itoa(relativePID))
+}
+
+// WorkerPID returns an instance that routes every command to the process with this pid (@!<pid>)
+// This also works for old workers that are still draining after a reload
+func (m *MasterT) WorkerPID(pid int) *HaproxyInstace {
+	return m.route("@!" + strconv.Itoa(pid))
+}
+
+// Workers returns an instance for every current worker
+func (m *MasterT) Workers() ([]*HaproxyInstace, error) {
+	return m.workers(false)
+}
+
+// OldWorkers returns an instance for every old worker that is still draining after a reload
+func (m *MasterT) OldWorkers() ([]*HaproxyInstace, error) {
+	return m.workers(true)
+}
+
+func (m *MasterT) workers(old bool) ([]*HaproxyInstace, error) {
+	toReturn := []*HaproxyInstace{}
+	procs, err := m.ShowProc()
+	if err != nil {
+		return toReturn, err
+	}
+	for _, proc := range procs {
+		if proc.Type != "worker" || proc.Old != old {
+			continue
+		}
+		toReturn = append(toReturn, m.WorkerPID(proc.PID))
+	}
+	return toReturn, nil
+}
+
+func (m *MasterT) route(prefix string) *HaproxyInstace {
+	// Copy the instance so the worker keeps the audit hook and interceptors
+	h := *m.h
+	h.prefix = prefix
+	return &h
+}
@@ -0,0 +1,138 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		expected  ReloadResultT
+		expectErr bool
+	}{
+		{
+			name: "success",
+			out: "Success=1\n" +
+				"--\n" +
+				"[NOTICE]   (1) : haproxy version is 2.7.0-f4a6e8b\n" +
+				"[NOTICE]   (1) : path to executable is /usr/local/sbin/haproxy\n",
+			expected: ReloadResultT{
+				Known:   true,
+				Success: true,
+				StartupLogs: "[NOTICE]   (1) : haproxy version is 2.7.0-f4a6e8b\n" +
+					"[NOTICE]   (1) : path to executable is /usr/local/sbin/haproxy",
+			},
+		},
+		{
+			name: "failed",
+			out: "Success=0\n" +
+				"--\n" +
+				"[NOTICE]   (1) : haproxy version is 2.7.0-f4a6e8b\n" +
+				"[ALERT]    (1) : config : parsing [/etc/haproxy/haproxy.cfg:12] : unknown keyword 'bnd' in 'frontend' section\n",
+			expected: ReloadResultT{
+				Known: true,
+				StartupLogs: "[NOTICE]   (1) : haproxy version is 2.7.0-f4a6e8b\n" +
+					"[ALERT]    (1) : config : parsing [/etc/haproxy/haproxy.cfg:12] : unknown keyword 'bnd' in 'frontend' section",
+			},
+		},
+		{
+			name:     "success without logs",
+			out:      "Success=1\n",
+			expected: ReloadResultT{Known: true, Success: true},
+		},
+		{
+			// Before haproxy 2.7 the master re-executes itself and drops the connection
+			name:     "before 2.7",
+			out:      "",
+			expected: ReloadResultT{},
+		},
+		{
+			name:      "unknown command",
+			out:       "Unknown command: 'reload', but maybe one of the following ones is a better match:\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Only "reload" is canned, reading the startup logs fails the test
+			m := cannedInstance(t, map[string]string{"reload": test.out}).Master()
+			result, err := m.Reload()
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("got %+v, expected %+v", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestShowProc(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		expected []ProcT
+	}{
+		{
+			name: "2.4 and newer",
+			out: "#<PID>          <type>          <reloads>       <uptime>        <version>\n" +
+				"1162            master          5 [failed: 1]   0d00h02m07s     2.4.0-14a6e0-4\n" +
+				"# workers\n" +
+				"1271            worker          1               0d00h00m00s     2.4.0-14a6e0-4\n" +
+				"1272            worker          1               0d00h00m00s     2.4.0-14a6e0-4\n" +
+				"# old workers\n" +
+				"1233            worker          3               0d00h00m43s     2.0-dev3-6019f6-289\n" +
+				"# programs\n" +
+				"1244            foo             0               0d00h00m00s     -\n" +
+				"1248            bar             0               1d02h00m20s     -\n",
+			expected: []ProcT{
+				{PID: 1162, Type: "master", Reloads: 5, Failed: 1, Uptime: 127 * time.Second, Version: "2.4.0-14a6e0-4"},
+				{PID: 1271, Type: "worker", RelativePID: 1, Reloads: 1, Version: "2.4.0-14a6e0-4"},
+				{PID: 1272, Type: "worker", RelativePID: 2, Reloads: 1, Version: "2.4.0-14a6e0-4"},
+				{PID: 1233, Type: "worker", Reloads: 3, Uptime: 43 * time.Second, Version: "2.0-dev3-6019f6-289", Old: true},
+				{PID: 1244, Type: "foo", Version: "-", Program: true},
+				{PID: 1248, Type: "bar", Uptime: 26*time.Hour + 20*time.Second, Version: "-", Program: true},
+			},
+		},
+		{
+			name: "before 2.4",
+			out: "#<PID>          <type>          <relative PID>  <reloads>       <uptime>        <version>\n" +
+				"14191           master          0               2               0d00h00m03s     2.2.0\n" +
+				"# workers\n" +
+				"14193           worker          1               0               0d00h00m01s     2.2.0\n" +
+				"# old workers\n" +
+				"14192           worker          [was: 1]        1               0d00h00m02s     2.2.0\n" +
+				"# programs\n" +
+				"14194           dataplane-api   -               0               0d00h00m01s     -\n",
+			expected: []ProcT{
+				{PID: 14191, Type: "master", Reloads: 2, Uptime: 3 * time.Second, Version: "2.2.0"},
+				{PID: 14193, Type: "worker", RelativePID: 1, Uptime: time.Second, Version: "2.2.0"},
+				{PID: 14192, Type: "worker", RelativePID: 1, Reloads: 1, Uptime: 2 * time.Second, Version: "2.2.0", Old: true},
+				{PID: 14194, Type: "dataplane-api", Uptime: time.Second, Version: "-", Program: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := cannedInstance(t, map[string]string{"show proc": test.out}).Master()
+			procs, err := m.ShowProc()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(procs, test.expected) {
+				t.Errorf("got\n%+v\nexpected\n%+v", procs, test.expected)
+			}
+		})
+	}
+}
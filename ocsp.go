@@ -0,0 +1,241 @@
+package haproxysocket
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// OCSPResponseT is an OCSP response known by haproxy
+type OCSPResponseT struct {
+	ID               string    `json:"id"` // The certificate ID key, used by ShowSSLOCSPResponse
+	CertPath         string    `json:"certPath"`
+	HashAlgorithm    string    `json:"hashAlgorithm"`
+	IssuerNameHash   string    `json:"issuerNameHash"`
+	IssuerKeyHash    string    `json:"issuerKeyHash"`
+	Serial           string    `json:"serial"`
+	ResponderID      string    `json:"responderId"`
+	ProducedAt       time.Time `json:"producedAt"`
+	Status           string    `json:"status"` // good, revoked or unknown
+	ThisUpdate       time.Time `json:"thisUpdate"`
+	NextUpdate       time.Time `json:"nextUpdate"`
+	RevocationTime   time.Time `json:"revocationTime"`
+	RevocationReason string    `json:"revocationReason"`
+}
+
+// SetSSLOCSPResponse update the OCSP response of a certificate, response is the DER encoded OCSP response
+func (h *HaproxyInstace) SetSSLOCSPResponse(response []byte) error {
+	if len(response) == 0 {
+		return errors.New("response can't be empty")
+	}
+	out, err := h.qPayload("set ssl ocsp-response", base64.StdEncoding.EncodeToString(response))
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "OCSP Response updated")
+}
+
+// UpdateSSLOCSPResponse let haproxy fetch a new OCSP response for a certificate itself
+// This requires haproxy 2.8 or newer and the ocsp-update option on the certificate
+func (h *HaproxyInstace) UpdateSSLOCSPResponse(certFile string) error {
+	if certFile == "" {
+		return errors.New("certFile can't be empty")
+	}
+	out, err := h.q("update ssl ocsp-response " + certFile)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return nil
+	}
+	return sslResult(out, "successful")
+}
+
+// ShowSSLOCSPResponses list the OCSP responses, only the ID, CertPath and certificate ID fields are filled
+func (h *HaproxyInstace) ShowSSLOCSPResponses() ([]OCSPResponseT, error) {
+	toReturn := []OCSPResponseT{}
+	out, err := h.q("show ssl ocsp-response")
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "#") {
+		return toReturn, errors.New(out)
+	}
+
+	// The output looks like:
+	// # Certificate IDs
+	//   - Certificate ID key : 303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a
+	//     Certificate path : /path_to_cert/foo.pem
+	//     Certificate ID:
+	//       Issuer Name Hash: 8A83E0060FAFF709CA7E9B95522A2E81635FDA0A
+	//       Issuer Key Hash: F652B0E435D5EA923851508F0ADBE92D85DE007A
+	//       Serial Number: 100A
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "- ") {
+			toReturn = append(toReturn, OCSPResponseT{})
+			line = strings.TrimPrefix(line, "- ")
+		}
+		if len(toReturn) == 0 {
+			continue
+		}
+		parseOCSPLine(&toReturn[len(toReturn)-1], line)
+	}
+	return toReturn, nil
+}
+
+// ShowSSLOCSPResponse report the details of an OCSP response, id is the certificate ID key from ShowSSLOCSPResponses
+func (h *HaproxyInstace) ShowSSLOCSPResponse(id string) (OCSPResponseT, error) {
+	toReturn := OCSPResponseT{ID: id}
+	if id == "" {
+		return toReturn, errors.New("id can't be empty")
+	}
+	out, err := h.q("show ssl ocsp-response " + id)
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "OCSP Response Data:") {
+		return toReturn, errors.New(out)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		parseOCSPLine(&toReturn, strings.TrimSpace(line))
+	}
+	return toReturn, nil
+}
+
+// parseOCSPLine sets the value of a "key: value" line from the OCSP output on r
+func parseOCSPLine(r *OCSPResponseT, line string) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return
+	}
+	value := strings.TrimSpace(parts[1])
+	switch strings.TrimSpace(parts[0]) {
+	case "Certificate ID key":
+		r.ID = value
+	case "Certificate path":
+		r.CertPath = value
+	case "Hash Algorithm":
+		r.HashAlgorithm = value
+	case "Issuer Name Hash":
+		r.IssuerNameHash = value
+	case "Issuer Key Hash":
+		r.IssuerKeyHash = value
+	case "Serial Number":
+		r.Serial = value
+	case "Responder Id":
+		r.ResponderID = value
+	case "Produced At":
+		r.ProducedAt = parseSSLDate(value)
+	case "Cert Status":
+		r.Status = value
+	case "This Update":
+		r.ThisUpdate = parseSSLDate(value)
+	case "Next Update":
+		r.NextUpdate = parseSSLDate(value)
+	case "Revocation Time":
+		r.RevocationTime = parseSSLDate(value)
+	case "Revocation Reason":
+		r.RevocationReason = value
+	}
+}
+
+// OCSPRefresherT replaces OCSP responses that are close to expiry, create one using OCSPRefresher
+type OCSPRefresherT struct {
+	h *HaproxyInstace
+
+	// Fetch must return a new DER encoded OCSP response for the certificate of current
+	Fetch func(current OCSPResponseT) ([]byte, error)
+
+	Before   time.Duration // Refresh responses that expire within this duration, defaults to 24 hours
+	Interval time.Duration // The time between checks when using Run, defaults to 1 hour
+
+	OnUpdate func(r OCSPResponseT)            // Optional, called after a response is replaced
+	OnError  func(r OCSPResponseT, err error) // Optional, called when replacing a response failed
+}
+
+// OCSPRefresher creates a refresher that uses fetch to get new OCSP responses
+func (h *HaproxyInstace) OCSPRefresher(fetch func(current OCSPResponseT) ([]byte, error)) *OCSPRefresherT {
+	return &OCSPRefresherT{
+		h:     h,
+		Fetch: fetch,
+	}
+}
+
+// RefreshOnce replaces all responses that expire within Before and returns the replaced responses
+func (r *OCSPRefresherT) RefreshOnce() ([]OCSPResponseT, error) {
+	refreshed := []OCSPResponseT{}
+	if r.Fetch == nil {
+		return refreshed, errors.New("Fetch can't be nil")
+	}
+	before := r.Before
+	if before == 0 {
+		before = 24 * time.Hour
+	}
+
+	errs := []string{}
+	fail := func(resp OCSPResponseT, err error) {
+		errs = append(errs, resp.ID+": "+err.Error())
+		if r.OnError != nil {
+			r.OnError(resp, err)
+		}
+	}
+
+	responses, err := r.h.ShowSSLOCSPResponses()
+	if err != nil {
+		// There is no response yet so OnError gets an empty OCSPResponseT
+		fail(OCSPResponseT{}, err)
+		return refreshed, err
+	}
+	for _, listed := range responses {
+		resp, err := r.h.ShowSSLOCSPResponse(listed.ID)
+		if err != nil {
+			fail(listed, err)
+			continue
+		}
+		resp.CertPath = listed.CertPath
+		if !resp.NextUpdate.IsZero() && time.Until(resp.NextUpdate) > before {
+			continue
+		}
+
+		der, err := r.Fetch(resp)
+		if err != nil {
+			fail(resp, err)
+			continue
+		}
+		err = r.h.SetSSLOCSPResponse(der)
+		if err != nil {
+			fail(resp, err)
+			continue
+		}
+		refreshed = append(refreshed, resp)
+		if r.OnUpdate != nil {
+			r.OnUpdate(resp)
+		}
+	}
+
+	if len(errs) > 0 {
+		return refreshed, errors.New(strings.Join(errs, ", "))
+	}
+	return refreshed, nil
+}
+
+// Run calls RefreshOnce every Interval until ctx is done
+// Errors are reported to OnError
+func (r *OCSPRefresherT) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Hour
+	}
+	for {
+		r.RefreshOnce()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
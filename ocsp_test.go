@@ -0,0 +1,156 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShowSSLOCSPResponses(t *testing.T) {
+	h := cannedInstance(t, map[string]string{
+		"show ssl ocsp-response": "# Certificate IDs\n" +
+			"  - Certificate ID key : 303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a0414f652b0e435d5ea923851508f0adbe92d85de007a0202100a\n" +
+			"    Certificate path : /etc/haproxy/certs/foo.pem\n" +
+			"    Certificate ID:\n" +
+			"      Issuer Name Hash: 8A83E0060FAFF709CA7E9B95522A2E81635FDA0A\n" +
+			"      Issuer Key Hash: F652B0E435D5EA923851508F0ADBE92D85DE007A\n" +
+			"      Serial Number: 100A\n" +
+			"  - Certificate ID key : 303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a0414f652b0e435d5ea923851508f0adbe92d85de007a0202100b\n" +
+			"    Certificate path : /etc/haproxy/certs/bar.pem\n" +
+			"    Certificate ID:\n" +
+			"      Issuer Name Hash: 8A83E0060FAFF709CA7E9B95522A2E81635FDA0A\n" +
+			"      Issuer Key Hash: F652B0E435D5EA923851508F0ADBE92D85DE007A\n" +
+			"      Serial Number: 100B\n",
+	})
+	responses, err := h.ShowSSLOCSPResponses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OCSPResponseT{
+		{
+			ID:             "303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a0414f652b0e435d5ea923851508f0adbe92d85de007a0202100a",
+			CertPath:       "/etc/haproxy/certs/foo.pem",
+			IssuerNameHash: "8A83E0060FAFF709CA7E9B95522A2E81635FDA0A",
+			IssuerKeyHash:  "F652B0E435D5EA923851508F0ADBE92D85DE007A",
+			Serial:         "100A",
+		},
+		{
+			ID:             "303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a0414f652b0e435d5ea923851508f0adbe92d85de007a0202100b",
+			CertPath:       "/etc/haproxy/certs/bar.pem",
+			IssuerNameHash: "8A83E0060FAFF709CA7E9B95522A2E81635FDA0A",
+			IssuerKeyHash:  "F652B0E435D5EA923851508F0ADBE92D85DE007A",
+			Serial:         "100B",
+		},
+	}
+	if !reflect.DeepEqual(responses, expected) {
+		t.Errorf("got %+v, expected %+v", responses, expected)
+	}
+}
+
+func TestShowSSLOCSPResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		expected  OCSPResponseT
+		expectErr bool
+	}{
+		{
+			name: "good",
+			out: "OCSP Response Data:\n" +
+				"    Version: 1 (0x0)\n" +
+				"    Responder Id: C = FR, O = HAProxy Technologies, CN = ocsp.haproxy.com\n" +
+				"    Produced At: May 27 15:43:38 2021 GMT\n" +
+				"    Responses:\n" +
+				"    Certificate ID:\n" +
+				"      Hash Algorithm: sha1\n" +
+				"      Issuer Name Hash: 8A83E0060FAFF709CA7E9B95522A2E81635FDA0A\n" +
+				"      Issuer Key Hash: F652B0E435D5EA923851508F0ADBE92D85DE007A\n" +
+				"      Serial Number: 100A\n" +
+				"    Cert Status: good\n" +
+				"    This Update: May 27 15:43:38 2021 GMT\n" +
+				"    Next Update: Oct 12 15:43:38 2048 GMT\n",
+			expected: OCSPResponseT{
+				ID:             "abc",
+				HashAlgorithm:  "sha1",
+				IssuerNameHash: "8A83E0060FAFF709CA7E9B95522A2E81635FDA0A",
+				IssuerKeyHash:  "F652B0E435D5EA923851508F0ADBE92D85DE007A",
+				Serial:         "100A",
+				ResponderID:    "C = FR, O = HAProxy Technologies, CN = ocsp.haproxy.com",
+				ProducedAt:     time.Date(2021, time.May, 27, 15, 43, 38, 0, time.UTC),
+				Status:         "good",
+				ThisUpdate:     time.Date(2021, time.May, 27, 15, 43, 38, 0, time.UTC),
+				NextUpdate:     time.Date(2048, time.October, 12, 15, 43, 38, 0, time.UTC),
+			},
+		},
+		{
+			name: "revoked",
+			out: "OCSP Response Data:\n" +
+				"    Version: 1 (0x0)\n" +
+				"    Responder Id: C = FR, O = HAProxy Technologies, CN = ocsp.haproxy.com\n" +
+				"    Produced At: Jun  3 09:12:00 2021 GMT\n" +
+				"    Responses:\n" +
+				"    Certificate ID:\n" +
+				"      Hash Algorithm: sha1\n" +
+				"      Issuer Name Hash: 8A83E0060FAFF709CA7E9B95522A2E81635FDA0A\n" +
+				"      Issuer Key Hash: F652B0E435D5EA923851508F0ADBE92D85DE007A\n" +
+				"      Serial Number: 100B\n" +
+				"    Cert Status: revoked\n" +
+				"    Revocation Time: Jun  1 10:00:00 2021 GMT\n" +
+				"    Revocation Reason: keyCompromise (0x1)\n" +
+				"    This Update: Jun  3 09:12:00 2021 GMT\n",
+			expected: OCSPResponseT{
+				ID:               "abc",
+				HashAlgorithm:    "sha1",
+				IssuerNameHash:   "8A83E0060FAFF709CA7E9B95522A2E81635FDA0A",
+				IssuerKeyHash:    "F652B0E435D5EA923851508F0ADBE92D85DE007A",
+				Serial:           "100B",
+				ResponderID:      "C = FR, O = HAProxy Technologies, CN = ocsp.haproxy.com",
+				ProducedAt:       time.Date(2021, time.June, 3, 9, 12, 0, 0, time.UTC),
+				Status:           "revoked",
+				ThisUpdate:       time.Date(2021, time.June, 3, 9, 12, 0, 0, time.UTC),
+				RevocationTime:   time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC),
+				RevocationReason: "keyCompromise (0x1)",
+			},
+		},
+		{
+			name:      "unknown id",
+			out:       "Certificate ID does not match any certificate.\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := cannedInstance(t, map[string]string{"show ssl ocsp-response abc": test.out})
+			response, err := h.ShowSSLOCSPResponse("abc")
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The times are parsed in the GMT location, compare them separately
+			times := [][2]time.Time{
+				{response.ProducedAt, test.expected.ProducedAt},
+				{response.ThisUpdate, test.expected.ThisUpdate},
+				{response.NextUpdate, test.expected.NextUpdate},
+				{response.RevocationTime, test.expected.RevocationTime},
+			}
+			for _, pair := range times {
+				if !pair[0].Equal(pair[1]) {
+					t.Errorf("got time %v, expected %v", pair[0], pair[1])
+				}
+			}
+			response.ProducedAt = test.expected.ProducedAt
+			response.ThisUpdate = test.expected.ThisUpdate
+			response.NextUpdate = test.expected.NextUpdate
+			response.RevocationTime = test.expected.RevocationTime
+			if response != test.expected {
+				t.Errorf("got\n%+v\nexpected\n%+v", response, test.expected)
+			}
+		})
+	}
+}
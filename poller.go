@@ -0,0 +1,191 @@
+package haproxysocket
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StatCounters are the "show stat" fields that only go up until they are reset
+var StatCounters = []string{
+	"stot", "bin", "bout", "dreq", "dresp", "ereq", "econ", "eresp", "wretr", "wredis",
+	"chkfail", "chkdown", "downtime", "lbtot", "hanafail",
+	"hrsp_1xx", "hrsp_2xx", "hrsp_3xx", "hrsp_4xx", "hrsp_5xx", "hrsp_other",
+	"req_tot", "cli_abrt", "srv_abrt", "comp_in", "comp_out", "comp_byp", "comp_rsp",
+	"conn_tot", "dcon", "dses", "wrew", "connect", "reuse", "cache_lookups", "cache_hits",
+	"intercepted", "eint",
+}
+
+// StatRowT is a single "show stat" row with the changes since the previous poll
+type StatRowT struct {
+	Proxy  string             `json:"proxy"`
+	Server string             `json:"server"` // FRONTEND, BACKEND or the name of the server or listener
+	Values map[string]string  `json:"values"` // The raw "show stat" row
+	Deltas map[string]uint64  `json:"deltas"` // The increase of every counter since the previous poll
+	Rates  map[string]float64 `json:"rates"`  // The increase of every counter per second
+	New    bool               `json:"new"`    // The row didn't exist in the previous poll, deltas and rates are empty
+	Reset  []string           `json:"reset"`  // The counters that went down since the previous poll
+}
+
+// StatSnapshotT is the result of a single poll
+type StatSnapshotT struct {
+	Time     time.Time         `json:"time"`
+	Interval time.Duration     `json:"interval"` // The time since the previous poll, 0 for the first poll
+	PID      string            `json:"pid"`
+	Uptime   time.Duration     `json:"uptime"`
+	Info     map[string]string `json:"info"` // The "show info" output
+	// Reset is true if haproxy restarted or reloaded since the previous poll,
+	// the deltas are then the counters since the restart
+	Reset bool       `json:"reset"`
+	Rows  []StatRowT `json:"rows"`
+	Err   error      `json:"-"`
+	Error string     `json:"error,omitempty"`
+}
+
+// StatPollerT samples "show stat" and computes deltas and rates, create one using StatPoller
+type StatPollerT struct {
+	h *HaproxyInstace
+	m sync.Mutex
+
+	Interval time.Duration // The time between polls when using Run, defaults to 10 seconds
+	Counters []string      // The fields to compute deltas and rates for, defaults to StatCounters
+
+	previous *StatSnapshotT
+	now      func() time.Time // Returns the time of a poll, replaced in the tests
+}
+
+// StatPoller creates a stats poller
+func (h *HaproxyInstace) StatPoller() *StatPollerT {
+	return &StatPollerT{
+		h:   h,
+		now: time.Now,
+	}
+}
+
+// Poll takes a sample and compares it with the previous one
+// A failed poll doesn't replace the previous sample so the next successful poll covers the whole time
+func (p *StatPollerT) Poll() StatSnapshotT {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	toReturn := StatSnapshotT{
+		Time: p.now(),
+		Info: map[string]string{},
+		Rows: []StatRowT{},
+	}
+	fail := func(err error) StatSnapshotT {
+		toReturn.Err = err
+		toReturn.Error = err.Error()
+		return toReturn
+	}
+
+	// "show info" and "show stat" are separate commands, haproxy might reload in between
+	// the info is read before and after the stats so a reload in between is detected
+	before, err := p.h.ShowInfo()
+	if err != nil {
+		return fail(err)
+	}
+	stats, err := p.h.ShowStat()
+	if err != nil {
+		return fail(err)
+	}
+	after, err := p.h.ShowInfo()
+	if err != nil {
+		return fail(err)
+	}
+	toReturn.Info = after
+	toReturn.PID = after["Pid"]
+	uptime, _ := strconv.ParseUint(after["Uptime_sec"], 10, 64)
+	toReturn.Uptime = time.Duration(uptime) * time.Second
+
+	counters := p.Counters
+	if len(counters) == 0 {
+		counters = StatCounters
+	}
+
+	previousRows := map[string]StatRowT{}
+	if p.previous != nil {
+		toReturn.Interval = toReturn.Time.Sub(p.previous.Time)
+		toReturn.Reset = before["Pid"] != after["Pid"] ||
+			toReturn.PID != p.previous.PID ||
+			toReturn.Uptime < p.previous.Uptime
+		if !toReturn.Reset {
+			for _, row := range p.previous.Rows {
+				previousRows[row.Proxy+"/"+row.Server] = row
+			}
+		}
+	}
+
+	for _, values := range stats {
+		row := StatRowT{
+			Proxy:  values["pxname"],
+			Server: values["svname"],
+			Values: values,
+			Deltas: map[string]uint64{},
+			Rates:  map[string]float64{},
+			Reset:  []string{},
+		}
+		previous, ok := previousRows[row.Proxy+"/"+row.Server]
+		if p.previous == nil || (!ok && !toReturn.Reset) {
+			row.New = p.previous != nil
+			toReturn.Rows = append(toReturn.Rows, row)
+			continue
+		}
+
+		for _, counter := range counters {
+			if values[counter] == "" {
+				continue
+			}
+			current := statUint(values, counter)
+			var delta uint64
+			switch {
+			case !ok:
+				// Haproxy restarted, the counters started at 0
+				delta = current
+			case current < statUint(previous.Values, counter):
+				// Reset using "clear counters" or a server that was re-added
+				delta = current
+				row.Reset = append(row.Reset, counter)
+			default:
+				delta = current - statUint(previous.Values, counter)
+			}
+			row.Deltas[counter] = delta
+			if toReturn.Interval > 0 {
+				row.Rates[counter] = float64(delta) / toReturn.Interval.Seconds()
+			}
+		}
+		toReturn.Rows = append(toReturn.Rows, row)
+	}
+
+	p.previous = &toReturn
+	return toReturn
+}
+
+// Run polls every Interval and sends the snapshots on the returned channel until ctx is done
+// Failed polls are also sent with Err set, the channel is closed when ctx is done
+func (p *StatPollerT) Run(ctx context.Context) <-chan StatSnapshotT {
+	interval := p.Interval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	snapshots := make(chan StatSnapshotT)
+	go func() {
+		defer close(snapshots)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case snapshots <- p.Poll():
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return snapshots
+}
@@ -0,0 +1,156 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sequenceInstance returns an instance that answers every query with the next output of its sequence
+func sequenceInstance(t *testing.T, sequences map[string][]string) *HaproxyInstace {
+	var m sync.Mutex
+	calls := map[string]int{}
+	h := New("unix", "/nonexistent.sock")
+	h.Interceptors = []InterceptorT{func(c *CommandT, next func() (string, error)) (string, error) {
+		m.Lock()
+		defer m.Unlock()
+		sequence := sequences[c.Query]
+		i := calls[c.Query]
+		if i >= len(sequence) {
+			t.Fatalf("unexpected query %q, call %v", c.Query, i+1)
+		}
+		calls[c.Query]++
+		return sequence[i], nil
+	}}
+	return h
+}
+
+const pollerStatHeader = "# pxname,svname,scur,stot,bin,bout,status,type,"
+
+func pollerInfo(pid, uptime string) string {
+	return "Name: HAProxy\nPid: " + pid + "\nUptime_sec: " + uptime
+}
+
+func TestStatPoller(t *testing.T) {
+	tests := []struct {
+		name           string
+		info           []string // 4 "show info" outputs, 2 for every poll
+		stat           []string // 2 "show stat" outputs
+		expectedReset  bool
+		expectedNew    bool
+		expectedDeltas map[string]uint64
+		expectedRates  map[string]float64
+		expectedResets []string
+	}{
+		{
+			name: "counters go up",
+			info: []string{pollerInfo("10", "100"), pollerInfo("10", "100"), pollerInfo("10", "110"), pollerInfo("10", "110")},
+			stat: []string{
+				pollerStatHeader + "\nbe_app,app1,3,100,5000,9000,UP,2,",
+				pollerStatHeader + "\nbe_app,app1,5,150,6000,9000,UP,2,",
+			},
+			expectedDeltas: map[string]uint64{"stot": 50, "bin": 1000, "bout": 0},
+			expectedRates:  map[string]float64{"stot": 5, "bin": 100, "bout": 0},
+			expectedResets: []string{},
+		},
+		{
+			name: "counter goes backwards",
+			info: []string{pollerInfo("10", "100"), pollerInfo("10", "100"), pollerInfo("10", "110"), pollerInfo("10", "110")},
+			stat: []string{
+				pollerStatHeader + "\nbe_app,app1,3,100,5000,9000,UP,2,",
+				// "clear counters all" between the polls
+				pollerStatHeader + "\nbe_app,app1,5,20,400,9100,UP,2,",
+			},
+			expectedDeltas: map[string]uint64{"stot": 20, "bin": 400, "bout": 100},
+			expectedRates:  map[string]float64{"stot": 2, "bin": 40, "bout": 10},
+			expectedResets: []string{"stot", "bin"},
+		},
+		{
+			name: "reload between the polls",
+			info: []string{pollerInfo("10", "100"), pollerInfo("10", "100"), pollerInfo("11", "5"), pollerInfo("11", "5")},
+			stat: []string{
+				pollerStatHeader + "\nbe_app,app1,3,100,5000,9000,UP,2,",
+				pollerStatHeader + "\nbe_app,app1,1,30,700,800,UP,2,",
+			},
+			expectedReset:  true,
+			expectedDeltas: map[string]uint64{"stot": 30, "bin": 700, "bout": 800},
+			expectedRates:  map[string]float64{"stot": 3, "bin": 70, "bout": 80},
+			expectedResets: []string{},
+		},
+		{
+			name: "restart during the poll",
+			// The pid changed between "show info" and "show stat"
+			info: []string{pollerInfo("10", "100"), pollerInfo("10", "100"), pollerInfo("10", "110"), pollerInfo("12", "1")},
+			stat: []string{
+				pollerStatHeader + "\nbe_app,app1,3,100,5000,9000,UP,2,",
+				pollerStatHeader + "\nbe_app,app1,1,2,10,20,UP,2,",
+			},
+			expectedReset:  true,
+			expectedDeltas: map[string]uint64{"stot": 2, "bin": 10, "bout": 20},
+			expectedRates:  map[string]float64{"stot": 0.2, "bin": 1, "bout": 2},
+			expectedResets: []string{},
+		},
+		{
+			name: "new server",
+			info: []string{pollerInfo("10", "100"), pollerInfo("10", "100"), pollerInfo("10", "110"), pollerInfo("10", "110")},
+			stat: []string{
+				pollerStatHeader + "\nbe_app,app1,3,100,5000,9000,UP,2,",
+				pollerStatHeader + "\nbe_app,app2,1,5,10,20,UP,2,",
+			},
+			expectedNew:    true,
+			expectedDeltas: map[string]uint64{},
+			expectedRates:  map[string]float64{},
+			expectedResets: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := sequenceInstance(t, map[string][]string{
+				"show info": test.info,
+				"show stat": test.stat,
+			}).StatPoller()
+			p.Counters = []string{"stot", "bin", "bout"}
+			start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+			times := []time.Time{start, start.Add(10 * time.Second)}
+			p.now = func() time.Time {
+				now := times[0]
+				times = times[1:]
+				return now
+			}
+
+			first := p.Poll()
+			if first.Err != nil {
+				t.Fatal(first.Err)
+			}
+			if first.Interval != 0 || first.Reset || first.Rows[0].New {
+				t.Errorf("unexpected first poll %+v", first)
+			}
+
+			second := p.Poll()
+			if second.Err != nil {
+				t.Fatal(second.Err)
+			}
+			if second.Interval != 10*time.Second {
+				t.Errorf("got interval %v, expected 10s", second.Interval)
+			}
+			if second.Reset != test.expectedReset {
+				t.Errorf("got reset %v, expected %v", second.Reset, test.expectedReset)
+			}
+			row := second.Rows[0]
+			if row.New != test.expectedNew {
+				t.Errorf("got new %v, expected %v", row.New, test.expectedNew)
+			}
+			if !reflect.DeepEqual(row.Deltas, test.expectedDeltas) {
+				t.Errorf("got deltas %v, expected %v", row.Deltas, test.expectedDeltas)
+			}
+			if !reflect.DeepEqual(row.Rates, test.expectedRates) {
+				t.Errorf("got rates %v, expected %v", row.Rates, test.expectedRates)
+			}
+			if !reflect.DeepEqual(row.Reset, test.expectedResets) {
+				t.Errorf("got reset counters %v, expected %v", row.Reset, test.expectedResets)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package haproxysocket
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HitlessReloadOptsT are the options for HitlessReload
+type HitlessReloadOptsT struct {
+	Timeout       time.Duration // The max time to wait for the new worker, defaults to 30 seconds
+	PollInterval  time.Duration // The time between "show proc" calls while waiting, defaults to 500 milliseconds
+	ExpectVersion string        // Optional, the version the new worker must report
+}
+
+// OldWorkerT is a worker from before the reload that still exists
+type OldWorkerT struct {
+	PID       int `json:"pid"`
+	CurrConns int `json:"currConns"` // -1 if unknown
+}
+
+// HitlessReloadT is the result of HitlessReload
+type HitlessReloadT struct {
+	NewWorker   ProcT             `json:"newWorker"`
+	Info        map[string]string `json:"info"` // "show info" of the new worker
+	StartupLogs string            `json:"startupLogs"`
+	Warnings    []string          `json:"warnings"`
+	Errors      []string          `json:"errors"` // The [ALERT] and [ERROR] lines from the startup logs
+	OldWorkers  []OldWorkerT      `json:"oldWorkers"`
+	Duration    time.Duration     `json:"duration"`
+}
+
+// HitlessReload reloads haproxy and verifies the new worker
+// It waits for the new worker to show up in "show proc", checks its pid and version using "show info",
+// reads the startup logs and reports the old workers that are still holding connections
+func (m *MasterT) HitlessReload(opts HitlessReloadOptsT) (HitlessReloadT, error) {
+	start := time.Now()
+	toReturn := HitlessReloadT{
+		Info:       map[string]string{},
+		Warnings:   []string{},
+		Errors:     []string{},
+		OldWorkers: []OldWorkerT{},
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval == 0 {
+		pollInterval = 500 * time.Millisecond
+	}
+
+	before, err := m.ShowProc()
+	if err != nil {
+		return toReturn, err
+	}
+	beforePIDs := map[int]bool{}
+	for _, proc := range before {
+		beforePIDs[proc.PID] = true
+	}
+
+	reload, err := m.Reload()
+	if err != nil {
+		return toReturn, err
+	}
+	toReturn.StartupLogs = reload.StartupLogs
+	toReturn.Warnings, toReturn.Errors = parseStartupLogs(reload.StartupLogs)
+	if reload.Known && !reload.Success {
+		return toReturn, errors.New("reload failed: " + strings.Join(toReturn.Errors, ", "))
+	}
+
+	var procs []ProcT
+	found := false
+	for {
+		procs, err = m.ShowProc()
+		if err == nil {
+			for _, proc := range procs {
+				if proc.Type == "worker" && !proc.Old && !beforePIDs[proc.PID] {
+					toReturn.NewWorker = proc
+					found = true
+					break
+				}
+			}
+		}
+		if found {
+			break
+		}
+		if time.Since(start) > timeout {
+			if len(toReturn.Errors) > 0 {
+				return toReturn, errors.New("no new worker started: " + strings.Join(toReturn.Errors, ", "))
+			}
+			return toReturn, errors.New("no new worker started within " + timeout.String())
+		}
+		time.Sleep(pollInterval)
+	}
+
+	if !reload.Known {
+		// Older haproxy versions, the startup logs are only complete after the worker started
+		logs, err := m.ShowStartupLogs()
+		if err == nil {
+			toReturn.StartupLogs = logs
+			toReturn.Warnings, toReturn.Errors = parseStartupLogs(logs)
+		}
+	}
+
+	toReturn.Info, err = m.WorkerPID(toReturn.NewWorker.PID).ShowInfo()
+	if err != nil {
+		return toReturn, err
+	}
+	if toReturn.Info["Pid"] != strconv.Itoa(toReturn.NewWorker.PID) {
+		return toReturn, errors.New("new worker reports pid " + toReturn.Info["Pid"] + ", expected " + strconv.Itoa(toReturn.NewWorker.PID))
+	}
+	if opts.ExpectVersion != "" && toReturn.Info["Version"] != opts.ExpectVersion {
+		return toReturn, errors.New("new worker reports version " + toReturn.Info["Version"] + ", expected " + opts.ExpectVersion)
+	}
+
+	for _, proc := range procs {
+		if proc.Type != "worker" || proc.PID == toReturn.NewWorker.PID {
+			continue
+		}
+		oldWorker := OldWorkerT{PID: proc.PID, CurrConns: -1}
+		info, err := m.WorkerPID(proc.PID).ShowInfo()
+		if err == nil {
+			conns, err := strconv.Atoi(info["CurrConns"])
+			if err == nil {
+				oldWorker.CurrConns = conns
+			}
+		}
+		toReturn.OldWorkers = append(toReturn.OldWorkers, oldWorker)
+	}
+
+	toReturn.Duration = time.Since(start)
+	return toReturn, nil
+}
+
+// parseStartupLogs returns the warnings and errors from the startup logs
+func parseStartupLogs(logs string) ([]string, []string) {
+	warnings := []string{}
+	errs := []string{}
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "[WARNING]"):
+			warnings = append(warnings, line)
+		case strings.HasPrefix(line, "[ALERT]"), strings.HasPrefix(line, "[ERROR]"):
+			errs = append(errs, line)
+		}
+	}
+	return warnings, errs
+}
@@ -0,0 +1,66 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStartupLogs(t *testing.T) {
+	tests := []struct {
+		name             string
+		logs             string
+		expectedWarnings []string
+		expectedErrors   []string
+	}{
+		{
+			name: "notices only",
+			logs: "[NOTICE]   (1) : haproxy version is 2.7.0-f4a6e8b\n" +
+				"[NOTICE]   (1) : path to executable is /usr/local/sbin/haproxy\n",
+			expectedWarnings: []string{},
+			expectedErrors:   []string{},
+		},
+		{
+			name: "warnings and errors",
+			logs: "[NOTICE]   (1) : haproxy version is 2.7.0-f4a6e8b\n" +
+				"[WARNING]  (1) : config : missing timeouts for backend 'be_app'.\n" +
+				"[ALERT]    (1) : config : parsing [/etc/haproxy/haproxy.cfg:12] : unknown keyword 'bnd' in 'frontend' section\n" +
+				"[ERROR]    (1) : config : Fatal errors found in configuration.\n",
+			expectedWarnings: []string{
+				"[WARNING]  (1) : config : missing timeouts for backend 'be_app'.",
+			},
+			expectedErrors: []string{
+				"[ALERT]    (1) : config : parsing [/etc/haproxy/haproxy.cfg:12] : unknown keyword 'bnd' in 'frontend' section",
+				"[ERROR]    (1) : config : Fatal errors found in configuration.",
+			},
+		},
+		{
+			name: "leading whitespace",
+			logs: "  [WARNING] 123/094512 (1) : Setting tune.ssl.default-dh-param to 1024 by default\n" +
+				"\t[ALERT] 123/094512 (1) : Starting frontend fe_http: cannot bind socket [0.0.0.0:80]\n",
+			expectedWarnings: []string{
+				"[WARNING] 123/094512 (1) : Setting tune.ssl.default-dh-param to 1024 by default",
+			},
+			expectedErrors: []string{
+				"[ALERT] 123/094512 (1) : Starting frontend fe_http: cannot bind socket [0.0.0.0:80]",
+			},
+		},
+		{
+			name:             "empty",
+			logs:             "",
+			expectedWarnings: []string{},
+			expectedErrors:   []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings, errs := parseStartupLogs(test.logs)
+			if !reflect.DeepEqual(warnings, test.expectedWarnings) {
+				t.Errorf("got warnings %q, expected %q", warnings, test.expectedWarnings)
+			}
+			if !reflect.DeepEqual(errs, test.expectedErrors) {
+				t.Errorf("got errors %q, expected %q", errs, test.expectedErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package haproxysocket
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SrvOpState is the operational state of a server (srv_op_state)
+type SrvOpState int
+
+// The possible operational states
+const (
+	SrvOpStopped  SrvOpState = 0
+	SrvOpStarting SrvOpState = 1
+	SrvOpRunning  SrvOpState = 2
+	SrvOpStopping SrvOpState = 3
+)
+
+func (s SrvOpState) String() string {
+	switch s {
+	case SrvOpStopped:
+		return "stopped"
+	case SrvOpStarting:
+		return "starting"
+	case SrvOpRunning:
+		return "running"
+	case SrvOpStopping:
+		return "stopping"
+	default:
+		return "unknown(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// SrvAdminState are the administrative state flags of a server (srv_admin_state)
+type SrvAdminState int
+
+// The admin state flags
+const (
+	SrvAdminFMaint SrvAdminState = 0x01 // Forced maintenance, set from the cli
+	SrvAdminIMaint SrvAdminState = 0x02 // Inherited maintenance from a tracked server
+	SrvAdminCMaint SrvAdminState = 0x04 // Maintenance from the configuration
+	SrvAdminFDrain SrvAdminState = 0x08 // Forced drain, set from the cli
+	SrvAdminIDrain SrvAdminState = 0x10 // Inherited drain from a tracked server
+	SrvAdminRMaint SrvAdminState = 0x20 // Maintenance because of a DNS resolution failure
+	SrvAdminHMaint SrvAdminState = 0x40 // Maintenance because the server has no address
+)
+
+// Maint returns true if the server is in maintenance for any reason
+func (s SrvAdminState) Maint() bool {
+	return s&(SrvAdminFMaint|SrvAdminIMaint|SrvAdminCMaint|SrvAdminRMaint|SrvAdminHMaint) != 0
+}
+
+// Drain returns true if the server is draining for any reason
+func (s SrvAdminState) Drain() bool {
+	return s&(SrvAdminFDrain|SrvAdminIDrain) != 0
+}
+
+// Forced returns the state as set from the cli, the value used by ServerT.State
+func (s SrvAdminState) Forced() string {
+	switch {
+	case s&SrvAdminFMaint != 0:
+		return "maint"
+	case s&SrvAdminFDrain != 0:
+		return "drain"
+	default:
+		return "ready"
+	}
+}
+
+// CheckState are the state flags of a health or agent check (srv_check_state, srv_agent_state)
+type CheckState int
+
+// The check state flags
+const (
+	CheckInProgress CheckState = 0x01
+	CheckConfigured CheckState = 0x02
+	CheckEnabled    CheckState = 0x04
+	CheckPaused     CheckState = 0x08
+	CheckAgent      CheckState = 0x10
+)
+
+// Configured returns true if this check is configured
+func (s CheckState) Configured() bool {
+	return s&CheckConfigured != 0
+}
+
+// Enabled returns true if this check is enabled
+func (s CheckState) Enabled() bool {
+	return s&CheckEnabled != 0
+}
+
+// ServerStateT is a single server from "show servers state"
+// The fields after SRVRecord are only filled by newer haproxy versions
+type ServerStateT struct {
+	BackendID           int               `json:"backendId"`
+	Backend             string            `json:"backend"`
+	ServerID            int               `json:"serverId"`
+	Server              string            `json:"server"`
+	Addr                string            `json:"addr"`
+	OpState             SrvOpState        `json:"opState"`
+	AdminState          SrvAdminState     `json:"adminState"`
+	UWeight             int               `json:"uweight"` // The user weight
+	IWeight             int               `json:"iweight"` // The initial weight
+	TimeSinceLastChange time.Duration     `json:"timeSinceLastChange"`
+	CheckStatus         int               `json:"checkStatus"` // The last health check status code
+	CheckResult         int               `json:"checkResult"` // 0 = unknown, 1 = neutral, 2 = failed, 3 = passed, 4 = conditionally passed
+	CheckHealth         int               `json:"checkHealth"` // The health check counter
+	CheckState          CheckState        `json:"checkState"`
+	AgentState          CheckState        `json:"agentState"`
+	BackendForcedID     bool              `json:"backendForcedId"` // The backend id was set in the config
+	ServerForcedID      bool              `json:"serverForcedId"`  // The server id was set in the config
+	FQDN                string            `json:"fqdn"`
+	Port                int               `json:"port"`
+	SRVRecord           string            `json:"srvRecord"`
+	UseSSL              bool              `json:"useSsl"`
+	CheckPort           int               `json:"checkPort"`
+	CheckAddr           string            `json:"checkAddr"`
+	AgentAddr           string            `json:"agentAddr"`
+	AgentPort           int               `json:"agentPort"`
+	Raw                 map[string]string `json:"raw"` // All columns, also the ones not known by this library
+}
+
+// ShowServersState dump volatile server information for a backend or for all backends if no backend is given
+func (h *HaproxyInstace) ShowServersState(backend ...string) ([]ServerStateT, error) {
+	toEx := "show servers state"
+	switch len(backend) {
+	case 0:
+	case 1:
+		if backend[0] == "" {
+			return []ServerStateT{}, errors.New("backend can't be empty")
+		}
+		toEx = toEx + " " + backend[0]
+	default:
+		return []ServerStateT{}, errors.New("There can't be more than 1 backend")
+	}
+
+	out, err := h.q(toEx)
+	if err != nil {
+		return []ServerStateT{}, err
+	}
+	if strings.Contains(out, "Can't find backend") {
+		return []ServerStateT{}, errors.New(out)
+	}
+	return parseServersState(out)
+}
+
+// parseServersState parses the output of "show servers state" (also used for the server-state-file)
+func parseServersState(in string) ([]ServerStateT, error) {
+	toReturn := []ServerStateT{}
+
+	lines := strings.Split(strings.TrimSpace(in), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return toReturn, errors.New("No output data")
+	}
+	switch strings.TrimSpace(lines[0]) {
+	case "1", "2":
+	default:
+		return toReturn, errors.New("unsupported \"show servers state ...\" output, only support version 1 and 2, recieved version " + lines[0])
+	}
+	if len(lines) == 1 {
+		return toReturn, nil
+	}
+
+	// When dumping all backends every backend has it's own header line, only the first one is used
+	// The "# " prefix is removed so the column names line up with the values
+	body := []string{strings.TrimPrefix(lines[1], "# ")}
+	for _, line := range lines[2:] {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		body = append(body, line)
+	}
+
+	rows, err := csvToArrMap(strings.Join(body, "\n"), " ")
+	if err != nil {
+		return toReturn, err
+	}
+	for _, row := range rows {
+		if row["srv_name"] == "" {
+			continue
+		}
+		toReturn = append(toReturn, serverStateFromMap(row))
+	}
+	return toReturn, nil
+}
+
+// serverStateFromMap converts a "show servers state" row to a ServerStateT
+func serverStateFromMap(row map[string]string) ServerStateT {
+	str := func(key string) string {
+		if row[key] == "-" {
+			return ""
+		}
+		return row[key]
+	}
+	num := func(key string) int {
+		i, _ := strconv.Atoi(row[key])
+		return i
+	}
+
+	return ServerStateT{
+		BackendID:           num("be_id"),
+		Backend:             row["be_name"],
+		ServerID:            num("srv_id"),
+		Server:              row["srv_name"],
+		Addr:                row["srv_addr"],
+		OpState:             SrvOpState(num("srv_op_state")),
+		AdminState:          SrvAdminState(num("srv_admin_state")),
+		UWeight:             num("srv_uweight"),
+		IWeight:             num("srv_iweight"),
+		TimeSinceLastChange: time.Duration(num("srv_time_since_last_change")) * time.Second,
+		CheckStatus:         num("srv_check_status"),
+		CheckResult:         num("srv_check_result"),
+		CheckHealth:         num("srv_check_health"),
+		CheckState:          CheckState(num("srv_check_state")),
+		AgentState:          CheckState(num("srv_agent_state")),
+		BackendForcedID:     num("bk_f_forced_id") == 1,
+		ServerForcedID:      num("srv_f_forced_id") == 1,
+		FQDN:                str("srv_fqdn"),
+		Port:                num("srv_port"),
+		SRVRecord:           str("srvrecord"),
+		UseSSL:              num("srv_use_ssl") == 1,
+		CheckPort:           num("srv_check_port"),
+		CheckAddr:           str("srv_check_addr"),
+		AgentAddr:           str("srv_agent_addr"),
+		AgentPort:           num("srv_agent_port"),
+		Raw:                 row,
+	}
+}
@@ -0,0 +1,167 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const serversStateHeader = "# be_id be_name srv_id srv_name srv_addr srv_op_state srv_admin_state srv_uweight srv_iweight " +
+	"srv_time_since_last_change srv_check_status srv_check_result srv_check_health srv_check_state srv_agent_state " +
+	"bk_f_forced_id srv_f_forced_id srv_fqdn srv_port srvrecord srv_use_ssl srv_check_port srv_check_addr srv_agent_addr srv_agent_port"
+
+func TestParseServersState(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		expected  []ServerStateT
+		expectErr bool
+	}{
+		{
+			name: "single backend",
+			out: "1\n" + serversStateHeader + "\n" +
+				"3 be_app 1 app1 10.0.0.1 2 0 1 1 1250 6 3 4 6 0 0 0 - 8080 - 0 0 - - 0\n" +
+				"3 be_app 2 app2 10.0.0.2 0 1 50 100 30 6 2 0 6 0 0 1 app2.example.com 8443 - 1 9000 10.0.1.2 - 0\n",
+			expected: []ServerStateT{
+				{
+					BackendID:           3,
+					Backend:             "be_app",
+					ServerID:            1,
+					Server:              "app1",
+					Addr:                "10.0.0.1",
+					OpState:             SrvOpRunning,
+					AdminState:          0,
+					UWeight:             1,
+					IWeight:             1,
+					TimeSinceLastChange: 1250 * time.Second,
+					CheckStatus:         6,
+					CheckResult:         3,
+					CheckHealth:         4,
+					CheckState:          6,
+					Port:                8080,
+				},
+				{
+					BackendID:           3,
+					Backend:             "be_app",
+					ServerID:            2,
+					Server:              "app2",
+					Addr:                "10.0.0.2",
+					OpState:             SrvOpStopped,
+					AdminState:          1,
+					UWeight:             50,
+					IWeight:             100,
+					TimeSinceLastChange: 30 * time.Second,
+					CheckStatus:         6,
+					CheckResult:         2,
+					CheckState:          6,
+					ServerForcedID:      true,
+					FQDN:                "app2.example.com",
+					Port:                8443,
+					UseSSL:              true,
+					CheckPort:           9000,
+					CheckAddr:           "10.0.1.2",
+				},
+			},
+		},
+		{
+			name: "all backends with a header per backend",
+			out: "1\n" + serversStateHeader + "\n" +
+				"3 be_app 1 app1 10.0.0.1 2 0 1 1 1250 6 3 4 6 0 0 0 - 8080 - 0 0 - - 0\n" +
+				"\n" + serversStateHeader + "\n" +
+				"4 be_static 1 static1 10.0.2.1 2 4 1 1 99 1 0 0 0 0 0 0 - 80 - 0 0 - - 0\n",
+			expected: []ServerStateT{
+				{
+					BackendID:           3,
+					Backend:             "be_app",
+					ServerID:            1,
+					Server:              "app1",
+					Addr:                "10.0.0.1",
+					OpState:             SrvOpRunning,
+					UWeight:             1,
+					IWeight:             1,
+					TimeSinceLastChange: 1250 * time.Second,
+					CheckStatus:         6,
+					CheckResult:         3,
+					CheckHealth:         4,
+					CheckState:          6,
+					Port:                8080,
+				},
+				{
+					BackendID:           4,
+					Backend:             "be_static",
+					ServerID:            1,
+					Server:              "static1",
+					Addr:                "10.0.2.1",
+					OpState:             SrvOpRunning,
+					AdminState:          4,
+					UWeight:             1,
+					IWeight:             1,
+					TimeSinceLastChange: 99 * time.Second,
+					CheckStatus:         1,
+					Port:                80,
+				},
+			},
+		},
+		{
+			name:     "backend without servers",
+			out:      "1\n" + serversStateHeader + "\n",
+			expected: []ServerStateT{},
+		},
+		{
+			name:      "unsupported version",
+			out:       "3\n" + serversStateHeader + "\n",
+			expectErr: true,
+		},
+		{
+			name:      "no output",
+			out:       "",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			states, err := parseServersState(test.out)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(states) != len(test.expected) {
+				t.Fatalf("got %v servers, expected %v", len(states), len(test.expected))
+			}
+			for i, state := range states {
+				if state.Raw["srv_name"] != test.expected[i].Server {
+					t.Errorf("server %v: raw srv_name is %q", i, state.Raw["srv_name"])
+				}
+				// Raw contains all columns, it's checked above
+				state.Raw = nil
+				if !reflect.DeepEqual(state, test.expected[i]) {
+					t.Errorf("server %v: got\n%+v\nexpected\n%+v", i, state, test.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSrvAdminState(t *testing.T) {
+	tests := []struct {
+		state  SrvAdminState
+		maint  bool
+		drain  bool
+		forced string
+	}{
+		{state: 0, forced: "ready"},
+		{state: 1, maint: true, forced: "maint"},
+		{state: 8, drain: true, forced: "drain"},
+	}
+	for _, test := range tests {
+		if test.state.Maint() != test.maint || test.state.Drain() != test.drain || test.state.Forced() != test.forced {
+			t.Errorf("state %v: got maint %v, drain %v, forced %v", int(test.state), test.state.Maint(), test.state.Drain(), test.state.Forced())
+		}
+	}
+}
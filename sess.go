@@ -0,0 +1,379 @@
+package haproxysocket
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessChannelT is the request or response channel of a session from the short "show sess" listing
+type SessChannelT struct {
+	Flags         string        `json:"flags"`
+	Input         uint64        `json:"input"`     // The amount of bytes in the input buffer
+	Analysers     string        `json:"analysers"` // The analysers bitfield
+	ReadExpire    time.Duration `json:"readExpire"`
+	WriteExpire   time.Duration `json:"writeExpire"`
+	AnalyseExpire time.Duration `json:"analyseExpire"`
+}
+
+// SessConnT is one side of a session from the short "show sess" listing
+type SessConnT struct {
+	State  int           `json:"state"`
+	Flags  string        `json:"flags"`
+	FD     int           `json:"fd"` // -1 if there is no file descriptor
+	Expire time.Duration `json:"expire"`
+}
+
+// parseSessLine parses a line of the short "show sess" listing, for example:
+// 0x55d0b7f2a800: proto=tcpv4 src=127.0.0.1:53870 fe=http be=test-backend srv=serv1 ts=00 epoch=0 age=4s calls=2
+// rq[f=848000h,i=0,an=00h,rx=59s,wx=,ax=] rp[f=80048000h,i=0,an=00h,rx=,wx=,ax=] s0=[8,200008h,fd=14,ex=] s1=[8,118h,fd=15,ex=] exp=59s
+func parseSessLine(line string) SessionT {
+	toReturn := SessionT{
+		RawRes: line,
+		Front:  SessConnT{FD: -1},
+		Back:   SessConnT{FD: -1},
+	}
+
+	for i, item := range strings.Split(line, " ") {
+		if i == 0 && len(item) > 0 {
+			toReturn.ID = strings.Replace(item, ":", "", 1)
+			continue
+		}
+
+		// Channels, rq[...] and rp[...]
+		if bracket := strings.Index(item, "["); bracket > 0 && !strings.Contains(item[:bracket], "=") {
+			channel := parseSessChannel(strings.TrimSuffix(item[bracket+1:], "]"))
+			switch item[:bracket] {
+			case "rq":
+				toReturn.Request = channel
+			case "rp":
+				toReturn.Response = channel
+			}
+			continue
+		}
+
+		nameAndVal := strings.SplitN(item, "=", 2)
+		if len(nameAndVal) < 2 {
+			continue
+		}
+		name := nameAndVal[0]
+		v := nameAndVal[1]
+
+		switch name {
+		case "proto":
+			toReturn.Type = v
+		case "src":
+			toReturn.Source = v
+		case "fe":
+			toReturn.Frontend = v
+		case "be":
+			toReturn.Backend = v
+		case "srv":
+			toReturn.Server = v
+		case "ts":
+			toReturn.TS = v
+		case "epoch":
+			toReturn.Epoch = parseSessUint(v)
+		case "age":
+			toReturn.Age = parseDuration(v)
+		case "calls":
+			toReturn.Calls = parseSessUint(v)
+		case "cpu":
+			toReturn.CPU = time.Duration(parseSessUint(v))
+		case "lat":
+			toReturn.Latency = time.Duration(parseSessUint(v))
+		case "exp":
+			toReturn.Expire = parseDuration(v)
+		case "s0", "scf":
+			toReturn.Front = parseSessConn(strings.Trim(v, "[]"))
+		case "s1", "scb":
+			toReturn.Back = parseSessConn(strings.Trim(v, "[]"))
+		}
+	}
+
+	return toReturn
+}
+
+// parseSessChannel parses the contents of rq[...] or rp[...]
+func parseSessChannel(in string) SessChannelT {
+	toReturn := SessChannelT{}
+	for _, part := range strings.Split(in, ",") {
+		nameAndVal := strings.SplitN(part, "=", 2)
+		if len(nameAndVal) < 2 {
+			continue
+		}
+		v := nameAndVal[1]
+		switch nameAndVal[0] {
+		case "f":
+			toReturn.Flags = v
+		case "i":
+			toReturn.Input = parseSessUint(v)
+		case "an":
+			toReturn.Analysers = v
+		case "rx":
+			toReturn.ReadExpire = parseDuration(v)
+		case "wx":
+			toReturn.WriteExpire = parseDuration(v)
+		case "ax":
+			toReturn.AnalyseExpire = parseDuration(v)
+		}
+	}
+	return toReturn
+}
+
+// parseSessConn parses the contents of s0=[...] or s1=[...]
+func parseSessConn(in string) SessConnT {
+	toReturn := SessConnT{FD: -1}
+	for i, part := range strings.Split(in, ",") {
+		switch i {
+		case 0:
+			toReturn.State, _ = strconv.Atoi(part)
+			continue
+		case 1:
+			toReturn.Flags = part
+			continue
+		}
+		nameAndVal := strings.SplitN(part, "=", 2)
+		if len(nameAndVal) < 2 {
+			continue
+		}
+		switch nameAndVal[0] {
+		case "fd":
+			fd, err := strconv.Atoi(nameAndVal[1])
+			if err == nil {
+				toReturn.FD = fd
+			}
+		case "ex":
+			toReturn.Expire = parseDuration(nameAndVal[1])
+		}
+	}
+	return toReturn
+}
+
+// parseSessUint parses a number from the sessions output, numbers with a h suffix or 0x prefix are hex
+func parseSessUint(in string) uint64 {
+	base := 10
+	if strings.HasSuffix(in, "h") {
+		in = strings.TrimSuffix(in, "h")
+		base = 16
+	} else if strings.HasPrefix(in, "0x") {
+		in = strings.TrimPrefix(in, "0x")
+		base = 16
+	}
+	i, _ := strconv.ParseUint(in, base, 64)
+	return i
+}
+
+// SessProxyT is the frontend, backend or server of a detailed session
+type SessProxyT struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+	Mode string `json:"mode"`
+	Addr string `json:"addr"`
+}
+
+// SessTaskT is the task that handles a detailed session
+type SessTaskT struct {
+	Ptr    string        `json:"ptr"`
+	State  string        `json:"state"`
+	Calls  uint64        `json:"calls"`
+	Expire time.Duration `json:"expire"`
+	Age    time.Duration `json:"age"`
+}
+
+// SessDetailConnT is a stream interface, stream connector or connection of a detailed session
+type SessDetailConnT struct {
+	Name  string            `json:"name"` // For example si[0], scf, co0
+	Ptr   string            `json:"ptr"`
+	Attrs map[string]string `json:"attrs"` // All key=value pairs of this line
+}
+
+// SessDetailChannelT is the request or response channel of a detailed session
+type SessDetailChannelT struct {
+	Ptr           string            `json:"ptr"`
+	Flags         string            `json:"flags"`
+	Analysers     string            `json:"analysers"`
+	Total         uint64            `json:"total"` // Total amount of bytes transferred
+	AnalyseExpire time.Duration     `json:"analyseExpire"`
+	ReadExpire    time.Duration     `json:"readExpire"`
+	WriteExpire   time.Duration     `json:"writeExpire"`
+	Attrs         map[string]string `json:"attrs"` // All key=value pairs of this channel
+}
+
+// SessDetailT are all details of a session from "show sess <id>" or "show sess all"
+type SessDetailT struct {
+	ID          string             `json:"id"`
+	Start       string             `json:"start"` // The accept date as shown by haproxy
+	StreamID    uint64             `json:"streamId"`
+	Type        string             `json:"type"`
+	Source      string             `json:"source"`
+	Flags       string             `json:"flags"`
+	ConnRetries int                `json:"connRetries"`
+	Epoch       uint64             `json:"epoch"`
+	Frontend    SessProxyT         `json:"frontend"`
+	Backend     SessProxyT         `json:"backend"`
+	Server      SessProxyT         `json:"server"`
+	Task        SessTaskT          `json:"task"`
+	Conns       []SessDetailConnT  `json:"conns"`
+	Request     SessDetailChannelT `json:"request"`
+	Response    SessDetailChannelT `json:"response"`
+	RawRes      string             `json:"rawRes"`
+}
+
+// ShowSessDetail dump all details of one session or all sessions if id is "all"
+func (h *HaproxyInstace) ShowSessDetail(id string) ([]SessDetailT, error) {
+	toReturn := []SessDetailT{}
+	if id == "" {
+		return toReturn, errors.New("ID can't be empty")
+	}
+
+	out, err := h.q("show sess " + id)
+	if err != nil {
+		return toReturn, err
+	}
+	if out == "" {
+		return toReturn, nil
+	}
+	if !strings.HasPrefix(out, "0x") {
+		return toReturn, errors.New(out)
+	}
+
+	// Every session starts with a not indented line
+	block := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "0x") && len(block) > 0 {
+			toReturn = append(toReturn, parseSessDetail(block))
+			block = []string{}
+		}
+		block = append(block, line)
+	}
+	if len(block) > 0 {
+		toReturn = append(toReturn, parseSessDetail(block))
+	}
+
+	return toReturn, nil
+}
+
+var sessKeyValRegex = regexp.MustCompile(`([A-Za-z_][\w.\[\]]*)=(\([^)]*\)|\S*)`)
+
+// sessKeyVals returns all key=value pairs of a line, the first occurrence of a key wins
+func sessKeyVals(line string) map[string]string {
+	toReturn := map[string]string{}
+	for _, match := range sessKeyValRegex.FindAllStringSubmatch(line, -1) {
+		if _, ok := toReturn[match[1]]; ok {
+			continue
+		}
+		v := strings.TrimRight(match[2], ",")
+		if !strings.HasPrefix(v, "(") {
+			// Values at the end of a group like "(id=2 mode=http)"
+			v = strings.TrimRight(v, ")")
+		}
+		toReturn[match[1]] = v
+	}
+	return toReturn
+}
+
+// parseSessDetail parses the lines of one session from "show sess <id>"
+func parseSessDetail(lines []string) SessDetailT {
+	toReturn := SessDetailT{
+		RawRes: strings.Join(lines, "\n"),
+		Conns:  []SessDetailConnT{},
+	}
+
+	// The first line looks like: 0x55d0b7f2a800: [19/Oct/2026:10:00:00.123456] id=5 proto=tcpv4 source=127.0.0.1:53870
+	first := lines[0]
+	if colon := strings.Index(first, ":"); colon > 0 {
+		toReturn.ID = first[:colon]
+	}
+	if start := strings.Index(first, "["); start > 0 {
+		if end := strings.Index(first[start:], "]"); end > 0 {
+			toReturn.Start = first[start+1 : start+end]
+		}
+	}
+	kv := sessKeyVals(first)
+	toReturn.StreamID = parseSessUint(kv["id"])
+	toReturn.Type = kv["proto"]
+	toReturn.Source = kv["source"]
+
+	// The indented lines after req= or res= belong to that channel
+	var channel *SessDetailChannelT
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		kv := sessKeyVals(line)
+		key := strings.SplitN(line, "=", 2)[0]
+
+		switch {
+		case key == "flags":
+			channel = nil
+			toReturn.Flags = kv["flags"]
+			toReturn.ConnRetries, _ = strconv.Atoi(kv["conn_retries"])
+			toReturn.Epoch = parseSessUint(kv["epoch"])
+		case key == "frontend":
+			channel = nil
+			toReturn.Frontend = sessProxy(kv, "frontend")
+		case key == "backend":
+			channel = nil
+			toReturn.Backend = sessProxy(kv, "backend")
+		case key == "server":
+			channel = nil
+			toReturn.Server = sessProxy(kv, "server")
+		case key == "task":
+			channel = nil
+			toReturn.Task = SessTaskT{
+				Ptr:    kv["task"],
+				State:  kv["state"],
+				Calls:  parseSessUint(kv["calls"]),
+				Expire: parseDuration(kv["exp"]),
+				Age:    parseDuration(kv["age"]),
+			}
+		case key == "req" || key == "res":
+			channel = &toReturn.Request
+			if key == "res" {
+				channel = &toReturn.Response
+			}
+			*channel = SessDetailChannelT{
+				Ptr:   kv[key],
+				Attrs: kv,
+			}
+			channel.Flags = kv["f"]
+			channel.Analysers = kv["an"]
+			channel.Total = parseSessUint(kv["total"])
+		case strings.HasPrefix(key, "si[") || strings.HasPrefix(key, "sc") || strings.HasPrefix(key, "co"):
+			channel = nil
+			toReturn.Conns = append(toReturn.Conns, SessDetailConnT{
+				Name:  key,
+				Ptr:   kv[key],
+				Attrs: kv,
+			})
+		case channel != nil:
+			for k, v := range kv {
+				if _, ok := channel.Attrs[k]; !ok {
+					channel.Attrs[k] = v
+				}
+			}
+			if v, ok := kv["an_exp"]; ok {
+				channel.AnalyseExpire = parseDuration(v)
+			}
+			if v, ok := kv["rex"]; ok {
+				channel.ReadExpire = parseDuration(v)
+			}
+			if v, ok := kv["wex"]; ok {
+				channel.WriteExpire = parseDuration(v)
+			}
+		}
+	}
+
+	return toReturn
+}
+
+// sessProxy converts a line like "frontend=http (id=2 mode=http), listener=? (id=1) addr=127.0.0.1:80"
+func sessProxy(kv map[string]string, key string) SessProxyT {
+	return SessProxyT{
+		Name: kv[key],
+		ID:   kv["id"],
+		Mode: kv["mode"],
+		Addr: kv["addr"],
+	}
+}
@@ -0,0 +1,90 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSessLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected SessionT
+	}{
+		{
+			name: "haproxy 2.0",
+			line: "0x55d0b7f2a800: proto=tcpv4 src=127.0.0.1:53870 fe=http be=test-backend srv=serv1 ts=00 epoch=0 age=4s calls=2 " +
+				"rq[f=848000h,i=0,an=00h,rx=59s,wx=,ax=] rp[f=80048000h,i=0,an=00h,rx=,wx=,ax=] s0=[8,200008h,fd=14,ex=] s1=[8,118h,fd=15,ex=] exp=59s",
+			expected: SessionT{
+				ID:       "0x55d0b7f2a800",
+				Type:     "tcpv4",
+				Source:   "127.0.0.1:53870",
+				Frontend: "http",
+				Backend:  "test-backend",
+				Server:   "serv1",
+				TS:       "00",
+				Age:      4 * time.Second,
+				Calls:    2,
+				Expire:   59 * time.Second,
+				Request:  SessChannelT{Flags: "848000h", Analysers: "00h", ReadExpire: 59 * time.Second},
+				Response: SessChannelT{Flags: "80048000h", Analysers: "00h"},
+				Front:    SessConnT{State: 8, Flags: "200008h", FD: 14},
+				Back:     SessConnT{State: 8, Flags: "118h", FD: 15},
+			},
+		},
+		{
+			name: "haproxy 2.6 with cpu, latency and stream connectors",
+			line: "0x7f5b34028ee0: proto=tcpv4 src=10.0.0.7:41542 fe=fe_main be=be_app srv=app1 ts=00 epoch=0x5 age=1m2s calls=3 rate=0 cpu=1200 lat=300 " +
+				"rq[f=848000h,i=512,an=00h,rx=58s,wx=,ax=] rp[f=80048000h,i=0,an=00h,rx=,wx=10s,ax=] scf=[8,1h,fd=17,ex=] scb=[8,1h,fd=18,ex=5s] exp=58s rc=0 c_exp=",
+			expected: SessionT{
+				ID:       "0x7f5b34028ee0",
+				Type:     "tcpv4",
+				Source:   "10.0.0.7:41542",
+				Frontend: "fe_main",
+				Backend:  "be_app",
+				Server:   "app1",
+				TS:       "00",
+				Epoch:    5,
+				CPU:      1200 * time.Nanosecond,
+				Latency:  300 * time.Nanosecond,
+				Age:      62 * time.Second,
+				Calls:    3,
+				Expire:   58 * time.Second,
+				Request:  SessChannelT{Flags: "848000h", Input: 512, Analysers: "00h", ReadExpire: 58 * time.Second},
+				Response: SessChannelT{Flags: "80048000h", Analysers: "00h", WriteExpire: 10 * time.Second},
+				Front:    SessConnT{State: 8, Flags: "1h", FD: 17},
+				Back:     SessConnT{State: 8, Flags: "1h", FD: 18, Expire: 5 * time.Second},
+			},
+		},
+		{
+			name: "session without server connection",
+			line: "0x55d0b7f2b000: proto=unix_stream src=unix:1 fe=GLOBAL be=<NONE> srv=<none> ts=00 epoch=0 age=0s calls=1 " +
+				"rq[f=c08000h,i=0,an=00h,rx=,wx=,ax=] rp[f=80008002h,i=0,an=00h,rx=,wx=,ax=] s0=[8,280008h,fd=16,ex=] s1=[8,204018h,fd=-1,ex=] exp=",
+			expected: SessionT{
+				ID:       "0x55d0b7f2b000",
+				Type:     "unix_stream",
+				Source:   "unix:1",
+				Frontend: "GLOBAL",
+				Backend:  "<NONE>",
+				Server:   "<none>",
+				TS:       "00",
+				Calls:    1,
+				Request:  SessChannelT{Flags: "c08000h", Analysers: "00h"},
+				Response: SessChannelT{Flags: "80008002h", Analysers: "00h"},
+				Front:    SessConnT{State: 8, Flags: "280008h", FD: 16},
+				Back:     SessConnT{State: 8, Flags: "204018h", FD: -1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.expected.RawRes = test.line
+			session := parseSessLine(test.line)
+			if !reflect.DeepEqual(session, test.expected) {
+				t.Errorf("got\n%+v\nexpected\n%+v", session, test.expected)
+			}
+		})
+	}
+}
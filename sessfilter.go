@@ -0,0 +1,188 @@
+package haproxysocket
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// SessFilterT selects sessions, empty fields are ignored and all set fields must match
+type SessFilterT struct {
+	Sources  []string // IPs or CIDRs, for example 10.0.0.1 or 10.0.0.0/8, matches if one of them matches
+	Frontend string
+	Backend  string
+	Server   string
+	MinAge   time.Duration // The session must be at least this old
+	MinCPU   time.Duration // The session must have used at least this much cpu time
+	MinCalls uint64        // The session must have been called at least this often
+
+	// The session must have been idle for this long, to detect this the sessions are listed twice
+	// with MinIdle in between and only the sessions without new calls are selected
+	MinIdle time.Duration
+}
+
+// sessSourceIP returns the ip of a session source like "127.0.0.1:53870" or "::1:53870"
+func sessSourceIP(source string) net.IP {
+	host, _, err := net.SplitHostPort(source)
+	if err != nil {
+		host = source
+		if i := strings.LastIndex(source, ":"); i > 0 {
+			host = source[:i]
+		}
+	}
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
+
+// parseSources converts the filter sources into networks
+func (f SessFilterT) parseSources() ([]*net.IPNet, error) {
+	toReturn := []*net.IPNet{}
+	for _, source := range f.Sources {
+		if !strings.Contains(source, "/") {
+			if strings.Contains(source, ":") {
+				source = source + "/128"
+			} else {
+				source = source + "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(source)
+		if err != nil {
+			return toReturn, err
+		}
+		toReturn = append(toReturn, network)
+	}
+	return toReturn, nil
+}
+
+// Match checks if a session matches the filter, MinIdle is not checked here
+// An error is returned if one of the Sources is not a valid IP or CIDR
+func (f SessFilterT) Match(s SessionT) (bool, error) {
+	networks, err := f.parseSources()
+	if err != nil {
+		return false, err
+	}
+	return f.match(s, networks), nil
+}
+
+func (f SessFilterT) match(s SessionT, networks []*net.IPNet) bool {
+	if f.Frontend != "" && s.Frontend != f.Frontend {
+		return false
+	}
+	if f.Backend != "" && s.Backend != f.Backend {
+		return false
+	}
+	if f.Server != "" && s.Server != f.Server {
+		return false
+	}
+	if s.Age < f.MinAge || s.CPU < f.MinCPU || s.Calls < f.MinCalls {
+		return false
+	}
+	if len(networks) > 0 {
+		ip := sessSourceIP(s.Source)
+		if ip == nil {
+			return false
+		}
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// QuerySess returns all sessions that match the filter
+func (h *HaproxyInstace) QuerySess(f SessFilterT) ([]SessionT, error) {
+	toReturn := []SessionT{}
+	networks, err := f.parseSources()
+	if err != nil {
+		return toReturn, err
+	}
+
+	sessions, err := h.ShowSess()
+	if err != nil {
+		return toReturn, err
+	}
+	for _, s := range sessions {
+		if f.match(s, networks) {
+			toReturn = append(toReturn, s)
+		}
+	}
+
+	if f.MinIdle == 0 || len(toReturn) == 0 {
+		return toReturn, nil
+	}
+
+	time.Sleep(f.MinIdle)
+	sessions, err = h.ShowSess()
+	if err != nil {
+		return []SessionT{}, err
+	}
+	calls := map[string]uint64{}
+	for _, s := range sessions {
+		calls[s.ID] = s.Calls
+	}
+	idle := []SessionT{}
+	for _, s := range toReturn {
+		newCalls, ok := calls[s.ID]
+		if ok && newCalls == s.Calls {
+			idle = append(idle, s)
+		}
+	}
+	return idle, nil
+}
+
+// KillSessOptsT are the options for KillSess
+type KillSessOptsT struct {
+	DryRun bool          // Only report the sessions that would be killed
+	Max    int           // The max amount of sessions to kill, 0 means no limit
+	Delay  time.Duration // The time to wait between every shutdown to limit the load on haproxy
+}
+
+// KillSessReportT is the result of KillSess
+type KillSessReportT struct {
+	DryRun  bool              `json:"dryRun"`
+	Matched []SessionT        `json:"matched"`
+	Killed  []string          `json:"killed"` // The IDs of the killed sessions
+	Failed  map[string]string `json:"failed"` // Session ID to error
+}
+
+// KillSess shuts down all sessions that match the filter
+func (h *HaproxyInstace) KillSess(f SessFilterT, opts KillSessOptsT) (KillSessReportT, error) {
+	report := KillSessReportT{
+		DryRun:  opts.DryRun,
+		Matched: []SessionT{},
+		Killed:  []string{},
+		Failed:  map[string]string{},
+	}
+
+	matched, err := h.QuerySess(f)
+	if err != nil {
+		return report, err
+	}
+	if opts.Max > 0 && len(matched) > opts.Max {
+		matched = matched[:opts.Max]
+	}
+	report.Matched = matched
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for i, s := range matched {
+		if i > 0 && opts.Delay > 0 {
+			time.Sleep(opts.Delay)
+		}
+		err := h.ShutdownSession(s.ID)
+		if err != nil {
+			report.Failed[s.ID] = err.Error()
+			continue
+		}
+		report.Killed = append(report.Killed, s.ID)
+	}
+
+	if len(report.Failed) > 0 {
+		return report, errors.New("failed to shutdown some sessions")
+	}
+	return report, nil
+}
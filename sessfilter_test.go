@@ -0,0 +1,59 @@
+package haproxysocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessFilterMatch(t *testing.T) {
+	session := SessionT{
+		Source:   "10.1.2.3:53870",
+		Frontend: "fe_http",
+		Backend:  "be_app",
+		Server:   "app1",
+		Age:      2 * time.Minute,
+		CPU:      5 * time.Millisecond,
+		Calls:    12,
+	}
+	ipv6Session := session
+	ipv6Session.Source = "[2001:db8::1]:53870"
+
+	tests := []struct {
+		name      string
+		filter    SessFilterT
+		session   SessionT
+		expected  bool
+		expectErr bool
+	}{
+		{name: "empty filter", session: session, expected: true},
+		{name: "ip", filter: SessFilterT{Sources: []string{"10.1.2.3"}}, session: session, expected: true},
+		{name: "cidr", filter: SessFilterT{Sources: []string{"192.168.0.0/16", "10.0.0.0/8"}}, session: session, expected: true},
+		{name: "other cidr", filter: SessFilterT{Sources: []string{"192.168.0.0/16"}}, session: session},
+		{name: "ipv6", filter: SessFilterT{Sources: []string{"2001:db8::/32"}}, session: ipv6Session, expected: true},
+		{name: "invalid cidr", filter: SessFilterT{Sources: []string{"10.0.0.0/33"}}, session: session, expectErr: true},
+		{name: "invalid ip", filter: SessFilterT{Sources: []string{"10.0.0.256"}}, session: session, expectErr: true},
+		{name: "backend and server", filter: SessFilterT{Backend: "be_app", Server: "app1"}, session: session, expected: true},
+		{name: "other server", filter: SessFilterT{Backend: "be_app", Server: "app2"}, session: session},
+		{name: "old enough", filter: SessFilterT{MinAge: time.Minute, MinCalls: 12}, session: session, expected: true},
+		{name: "too young", filter: SessFilterT{MinAge: time.Hour}, session: session},
+		{name: "not enough cpu", filter: SessFilterT{MinCPU: time.Second}, session: session},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, err := test.filter.Match(test.session)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != test.expected {
+				t.Errorf("got %v, expected %v", match, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package haproxysocket
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The columns of a server-state-file
+// haproxy versions before 2.4 only know the first 20 columns
+var serverStateColumns = []string{
+	"be_id", "be_name", "srv_id", "srv_name", "srv_addr", "srv_op_state", "srv_admin_state",
+	"srv_uweight", "srv_iweight", "srv_time_since_last_change", "srv_check_status", "srv_check_result",
+	"srv_check_health", "srv_check_state", "srv_agent_state", "bk_f_forced_id", "srv_f_forced_id",
+	"srv_fqdn", "srv_port", "srvrecord", "srv_use_ssl", "srv_check_port", "srv_check_addr",
+	"srv_agent_addr", "srv_agent_port",
+}
+
+// SnapshotT is the runtime state of all servers
+type SnapshotT struct {
+	Time    time.Time      `json:"time"`
+	Servers []ServerStateT `json:"servers"`
+}
+
+// dashIfEmpty returns "-" for empty strings as used by the server-state-file
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// boolToInt converts a bool to 1 or 0
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Snapshot captures the runtime state of all servers in all backends
+func (h *HaproxyInstace) Snapshot() (SnapshotT, error) {
+	servers, err := h.ShowServersState()
+	if err != nil {
+		return SnapshotT{Time: time.Now(), Servers: []ServerStateT{}}, err
+	}
+	return SnapshotT{
+		Time:    time.Now(),
+		Servers: servers,
+	}, nil
+}
+
+// WriteStateFile writes the snapshot in haproxy's server-state-file format
+// The output can be loaded by haproxy using the "server-state-file" global setting
+func (s SnapshotT) WriteStateFile(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "1\n# %v\n", strings.Join(serverStateColumns, " "))
+	if err != nil {
+		return err
+	}
+	for _, srv := range s.Servers {
+		_, err = fmt.Fprintf(
+			w,
+			"%v %v %v %v %v %d %d %v %v %v %v %v %v %d %d %v %v %v %v %v %v %v %v %v %v\n",
+			srv.BackendID, srv.Backend, srv.ServerID, srv.Server, srv.Addr, srv.OpState, srv.AdminState,
+			srv.UWeight, srv.IWeight, int(srv.TimeSinceLastChange.Seconds()), srv.CheckStatus, srv.CheckResult,
+			srv.CheckHealth, srv.CheckState, srv.AgentState, boolToInt(srv.BackendForcedID), boolToInt(srv.ServerForcedID),
+			dashIfEmpty(srv.FQDN), srv.Port, dashIfEmpty(srv.SRVRecord), boolToInt(srv.UseSSL), srv.CheckPort,
+			dashIfEmpty(srv.CheckAddr), dashIfEmpty(srv.AgentAddr), srv.AgentPort,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseStateFile reads a snapshot from haproxy's server-state-file format
+func ParseStateFile(r io.Reader) (SnapshotT, error) {
+	toReturn := SnapshotT{
+		Time:    time.Now(),
+		Servers: []ServerStateT{},
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return toReturn, err
+	}
+	servers, err := parseServersState(string(data))
+	if err != nil {
+		return toReturn, err
+	}
+	toReturn.Servers = servers
+	return toReturn, nil
+}
+
+// RestoreOptsT are the options for Restore
+type RestoreOptsT struct {
+	Health bool // Also force the health (up, stopping or down) from the snapshot
+	DryRun bool // Only report the commands that would be executed
+}
+
+// RestoreReportT is the result of Restore
+type RestoreReportT struct {
+	Commands []string `json:"commands"` // The executed (or with DryRun the to be executed) commands
+	Missing  []string `json:"missing"`  // backend/server from the snapshot that don't exist on the instance
+	Errors   []string `json:"errors"`
+}
+
+// Restore applies a snapshot onto the running instance by issuing the necessary "set server" commands
+// Only the values that differ from the current state are changed
+func (h *HaproxyInstace) Restore(s SnapshotT, opts RestoreOptsT) (RestoreReportT, error) {
+	report := RestoreReportT{
+		Commands: []string{},
+		Missing:  []string{},
+		Errors:   []string{},
+	}
+
+	current, err := h.Snapshot()
+	if err != nil {
+		return report, err
+	}
+	currentServers := map[string]ServerStateT{}
+	for _, srv := range current.Servers {
+		currentServers[srv.Backend+"/"+srv.Server] = srv
+	}
+
+	for _, wanted := range s.Servers {
+		name := wanted.Backend + "/" + wanted.Server
+		now, ok := currentServers[name]
+		if !ok {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+
+		run := func(command string, fn func() error) {
+			report.Commands = append(report.Commands, command)
+			if opts.DryRun {
+				return
+			}
+			err := fn()
+			if err != nil {
+				report.Errors = append(report.Errors, command+": "+err.Error())
+			}
+		}
+		server := h.Server(wanted.Backend, wanted.Server)
+
+		if wanted.Addr != now.Addr || wanted.Port != now.Port {
+			if wanted.Port == 0 {
+				// Servers without a port or with a port mapping, only the address can be set
+				run("set server "+name+" addr "+wanted.Addr, func() error {
+					return server.Addr(wanted.Addr)
+				})
+			} else {
+				port := strconv.Itoa(wanted.Port)
+				run("set server "+name+" addr "+wanted.Addr+" port "+port, func() error {
+					return server.Addr(wanted.Addr, port)
+				})
+			}
+		}
+		if wanted.FQDN != "" && wanted.FQDN != now.FQDN {
+			run("set server "+name+" fqdn "+wanted.FQDN, func() error {
+				return server.FQDN(wanted.FQDN)
+			})
+		}
+		if wanted.UWeight != now.UWeight {
+			weight := strconv.Itoa(wanted.UWeight)
+			run("set server "+name+" weight "+weight, func() error {
+				return server.Weight(weight)
+			})
+		}
+
+		if wanted.CheckPort != 0 && wanted.CheckPort != now.CheckPort {
+			port := strconv.Itoa(wanted.CheckPort)
+			run("set server "+name+" check-port "+port, func() error {
+				return server.CheckPort(port)
+			})
+		}
+		if wanted.AgentAddr != "" && wanted.AgentAddr != now.AgentAddr {
+			run("set server "+name+" agent-addr "+wanted.AgentAddr, func() error {
+				return server.AgentAddr(wanted.AgentAddr)
+			})
+		}
+
+		wantedState := wanted.AdminState.Forced()
+		if wantedState != now.AdminState.Forced() {
+			run("set server "+name+" state "+wantedState, func() error {
+				return server.State(wantedState)
+			})
+		}
+
+		for _, check := range []struct {
+			kind   string
+			wanted CheckState
+			now    CheckState
+		}{
+			{"health", wanted.CheckState, now.CheckState},
+			{"agent", wanted.AgentState, now.AgentState},
+		} {
+			if !check.wanted.Configured() || !check.now.Configured() {
+				continue
+			}
+			wantedEnabled := check.wanted.Enabled()
+			if wantedEnabled == check.now.Enabled() {
+				continue
+			}
+			command := "disable " + check.kind + " " + name
+			if wantedEnabled {
+				command = "enable " + check.kind + " " + name
+			}
+			run(command, func() error {
+				out, err := h.q(command)
+				if err != nil {
+					return err
+				}
+				if out != "" {
+					return errors.New(out)
+				}
+				return nil
+			})
+		}
+
+		if opts.Health && wanted.OpState != now.OpState {
+			health := ""
+			switch wanted.OpState {
+			case SrvOpStopped:
+				health = "down"
+			case SrvOpRunning:
+				health = "up"
+			case SrvOpStopping:
+				health = "stopping"
+			}
+			if health != "" {
+				run("set server "+name+" health "+health, func() error {
+					return server.Health(health)
+				})
+			}
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return report, errors.New(strings.Join(report.Errors, ", "))
+	}
+	return report, nil
+}
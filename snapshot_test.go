@@ -0,0 +1,58 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRestoreAddr(t *testing.T) {
+	current := "1\n" + serversStateHeader + "\n" +
+		"3 be_app 1 app1 10.0.0.1 2 0 1 1 1250 6 3 4 6 0 0 0 - 0 - 0 0 - - 0\n" +
+		"3 be_app 2 app2 10.0.0.2 2 0 1 1 1250 6 3 4 6 0 0 0 - 8080 - 0 0 - - 0\n"
+
+	tests := []struct {
+		name     string
+		wanted   ServerStateT
+		response map[string]string
+		expected []string
+	}{
+		{
+			name:   "without port",
+			wanted: ServerStateT{Backend: "be_app", Server: "app1", Addr: "10.0.0.9", UWeight: 1},
+			response: map[string]string{
+				"set server be_app/app1 addr 10.0.0.9": "IP changed from '10.0.0.1' to '10.0.0.9' by 'stats socket command'",
+			},
+			expected: []string{"set server be_app/app1 addr 10.0.0.9"},
+		},
+		{
+			name:   "with port",
+			wanted: ServerStateT{Backend: "be_app", Server: "app2", Addr: "10.0.0.2", Port: 8081, UWeight: 1},
+			response: map[string]string{
+				"set server be_app/app2 addr 10.0.0.2 port 8081": "no need to change the addr, port changed from '8080' to '8081' by 'stats socket command'",
+			},
+			expected: []string{"set server be_app/app2 addr 10.0.0.2 port 8081"},
+		},
+		{
+			name:     "unchanged",
+			wanted:   ServerStateT{Backend: "be_app", Server: "app1", Addr: "10.0.0.1", UWeight: 1},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responses := map[string]string{"show servers state": current}
+			for query, out := range test.response {
+				responses[query] = out
+			}
+			h := cannedInstance(t, responses)
+			report, err := h.Restore(SnapshotT{Servers: []ServerStateT{test.wanted}}, RestoreOptsT{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(report.Commands, test.expected) {
+				t.Errorf("got commands %q, expected %q", report.Commands, test.expected)
+			}
+		})
+	}
+}
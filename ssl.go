@@ -0,0 +1,326 @@
+package haproxysocket
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// qPayload executes a query with a payload, the payload is send using the "<<" syntax
+// Empty lines are removed from the payload as haproxy uses them to detect the end of the payload
+func (h *HaproxyInstace) qPayload(query, payload string) (string, error) {
+	lines := []string{}
+	for _, line := range strings.Split(payload, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return h.q(query + " <<\n" + strings.Join(lines, "\n") + "\n")
+}
+
+// sslResult returns an error if out doesn't contain one of the success messages, the messages are not case sensitive
+func sslResult(out string, success ...string) error {
+	lowerOut := strings.ToLower(out)
+	for _, s := range success {
+		if strings.Contains(lowerOut, strings.ToLower(s)) {
+			return nil
+		}
+	}
+	if out == "" {
+		return errors.New("No output")
+	}
+	return errors.New(out)
+}
+
+// NewSSLCert create a new empty certificate store that can be filled using SetSSLCert
+func (h *HaproxyInstace) NewSSLCert(file string) error {
+	if file == "" {
+		return errors.New("file can't be empty")
+	}
+	out, err := h.q("new ssl cert " + file)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "New empty certificate store")
+}
+
+// SetSSLCert upload a certificate to a transaction, the transaction is created if it doesn't exist yet
+// The payload is a PEM bundle, to upload the extra files add the .key, .ocsp, .issuer or .sctl extension to file
+func (h *HaproxyInstace) SetSSLCert(file, payload string) error {
+	if file == "" || payload == "" {
+		return errors.New("file and payload can't be empty")
+	}
+	out, err := h.qPayload("set ssl cert "+file, payload)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "Transaction created", "Transaction updated")
+}
+
+// CommitSSLCert commit the transaction of a certificate
+func (h *HaproxyInstace) CommitSSLCert(file string) error {
+	if file == "" {
+		return errors.New("file can't be empty")
+	}
+	out, err := h.q("commit ssl cert " + file)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "Success!")
+}
+
+// AbortSSLCert abort the transaction of a certificate
+func (h *HaproxyInstace) AbortSSLCert(file string) error {
+	if file == "" {
+		return errors.New("file can't be empty")
+	}
+	out, err := h.q("abort ssl cert " + file)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "Transaction aborted")
+}
+
+// DelSSLCert delete an unused certificate
+func (h *HaproxyInstace) DelSSLCert(file string) error {
+	if file == "" {
+		return errors.New("file can't be empty")
+	}
+	out, err := h.q("del ssl cert " + file)
+	if err != nil {
+		return err
+	}
+	return sslResult(out, "deleted!")
+}
+
+// SSLCertListT is the list of loaded certificates
+type SSLCertListT struct {
+	Transaction string   `json:"transaction"` // The certificate with an uncommitted transaction, empty if there is none
+	Files       []string `json:"files"`
+}
+
+// ShowSSLCerts list all loaded certificates and the current transaction
+func (h *HaproxyInstace) ShowSSLCerts() (SSLCertListT, error) {
+	toReturn := SSLCertListT{Files: []string{}}
+	out, err := h.q("show ssl cert")
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "#") {
+		return toReturn, errors.New(out)
+	}
+
+	// The output looks like:
+	// # transaction
+	// *test.pem
+	// # filename
+	// test.pem
+	section := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		switch section {
+		case "transaction":
+			toReturn.Transaction = strings.TrimPrefix(line, "*")
+		case "filename":
+			toReturn.Files = append(toReturn.Files, line)
+		}
+	}
+	return toReturn, nil
+}
+
+// SSLChainCertT is a certificate of the chain
+type SSLChainCertT struct {
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+}
+
+// SSLCertT are the details of a certificate
+type SSLCertT struct {
+	Filename        string            `json:"filename"`
+	Status          string            `json:"status"` // Empty, Unused, Used or Uncommitted
+	Serial          string            `json:"serial"`
+	NotBefore       time.Time         `json:"notBefore"`
+	NotAfter        time.Time         `json:"notAfter"`
+	SANs            []string          `json:"sans"` // The subject alternative names without the DNS: or IP Address: prefix
+	Algorithm       string            `json:"algorithm"`
+	SHA1            string            `json:"sha1"`
+	Subject         string            `json:"subject"`
+	Issuer          string            `json:"issuer"`
+	Chain           []SSLChainCertT   `json:"chain"`
+	ChainComplete   bool              `json:"chainComplete"` // The issuer of the certificate is present in the chain
+	OCSPResponseKey string            `json:"ocspResponseKey"`
+	Raw             map[string]string `json:"raw"`
+}
+
+// parseSSLDate parses the dates from "show ssl cert", for example "Sep 14 12:00:00 2021 GMT"
+func parseSSLDate(in string) time.Time {
+	t, err := time.Parse("Jan _2 15:04:05 2006 MST", strings.Join(strings.Fields(in), " "))
+	if err != nil {
+		t, err = time.Parse("Jan 2 15:04:05 2006 MST", strings.Join(strings.Fields(in), " "))
+		if err != nil {
+			return time.Time{}
+		}
+	}
+	return t
+}
+
+// ShowSSLCert report the details of a certificate, use *<file> to show the uncommitted transaction
+func (h *HaproxyInstace) ShowSSLCert(file string) (SSLCertT, error) {
+	toReturn := SSLCertT{
+		SANs:  []string{},
+		Chain: []SSLChainCertT{},
+		Raw:   map[string]string{},
+	}
+	if file == "" {
+		return toReturn, errors.New("file can't be empty")
+	}
+	out, err := h.q("show ssl cert " + file)
+	if err != nil {
+		return toReturn, err
+	}
+	if !strings.HasPrefix(out, "Filename:") {
+		return toReturn, errors.New(out)
+	}
+
+	return parseSSLCert(strings.Split(out, "\n")), nil
+}
+
+// parseSSLCert parses the details of a single certificate as shown by "show ssl cert <file>"
+func parseSSLCert(lines []string) SSLCertT {
+	toReturn := SSLCertT{
+		SANs:  []string{},
+		Chain: []SSLChainCertT{},
+		Raw:   map[string]string{},
+	}
+
+	for _, line := range lines {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if _, ok := toReturn.Raw[key]; !ok {
+			toReturn.Raw[key] = value
+		}
+
+		switch key {
+		case "Filename":
+			toReturn.Filename = value
+		case "Status":
+			toReturn.Status = value
+		case "Serial":
+			toReturn.Serial = value
+		case "notBefore":
+			toReturn.NotBefore = parseSSLDate(value)
+		case "notAfter":
+			toReturn.NotAfter = parseSSLDate(value)
+		case "Subject Alternative Name":
+			for _, san := range strings.Split(value, ",") {
+				san = strings.TrimSpace(san)
+				if i := strings.Index(san, ":"); i >= 0 {
+					san = san[i+1:]
+				}
+				if san != "" {
+					toReturn.SANs = append(toReturn.SANs, san)
+				}
+			}
+		case "Algorithm":
+			toReturn.Algorithm = value
+		case "SHA1 FingerPrint":
+			toReturn.SHA1 = value
+		case "Subject":
+			toReturn.Subject = value
+		case "Issuer":
+			toReturn.Issuer = value
+		case "Chain Subject":
+			toReturn.Chain = append(toReturn.Chain, SSLChainCertT{Subject: value})
+		case "Chain Issuer":
+			if len(toReturn.Chain) > 0 {
+				toReturn.Chain[len(toReturn.Chain)-1].Issuer = value
+			}
+		case "OCSP Response Key":
+			toReturn.OCSPResponseKey = value
+		}
+	}
+
+	for _, chainCert := range toReturn.Chain {
+		if chainCert.Subject == toReturn.Issuer {
+			toReturn.ChainComplete = true
+		}
+	}
+	if toReturn.Subject != "" && toReturn.Subject == toReturn.Issuer {
+		// Self signed
+		toReturn.ChainComplete = true
+	}
+
+	return toReturn
+}
+
+// CertBundleT are all files of a certificate that can be uploaded using UpdateSSLCert
+// Cert is required, the other fields are optional
+type CertBundleT struct {
+	Cert   string // PEM encoded certificate
+	Key    string // PEM encoded private key, when empty the key must be part of Cert
+	Chain  string // PEM encoded intermediate certificates
+	OCSP   []byte // DER encoded OCSP response
+	Issuer string // PEM encoded issuer certificate used for OCSP
+	SCTL   []byte // Signed certificate timestamp list
+}
+
+// UpdateSSLCert uploads a certificate bundle in one transaction and commits it
+// If something goes wrong the transaction is aborted
+func (h *HaproxyInstace) UpdateSSLCert(file string, bundle CertBundleT) error {
+	if bundle.Cert == "" {
+		return errors.New("bundle.Cert can't be empty")
+	}
+
+	pem := strings.TrimSpace(bundle.Cert)
+	if bundle.Key != "" {
+		pem = pem + "\n" + strings.TrimSpace(bundle.Key)
+	}
+	if bundle.Chain != "" {
+		pem = pem + "\n" + strings.TrimSpace(bundle.Chain)
+	}
+
+	err := h.SetSSLCert(file, pem)
+	if err != nil {
+		return err
+	}
+
+	// The extension and payload of the extra files
+	extras := [][2]string{}
+	if len(bundle.OCSP) > 0 {
+		extras = append(extras, [2]string{".ocsp", base64.StdEncoding.EncodeToString(bundle.OCSP)})
+	}
+	if bundle.Issuer != "" {
+		extras = append(extras, [2]string{".issuer", bundle.Issuer})
+	}
+	if len(bundle.SCTL) > 0 {
+		extras = append(extras, [2]string{".sctl", base64.StdEncoding.EncodeToString(bundle.SCTL)})
+	}
+	for _, extra := range extras {
+		err = h.SetSSLCert(file+extra[0], extra[1])
+		if err != nil {
+			h.AbortSSLCert(file)
+			return err
+		}
+	}
+
+	err = h.CommitSSLCert(file)
+	if err != nil {
+		h.AbortSSLCert(file)
+		return err
+	}
+	return nil
+}
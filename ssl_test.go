@@ -0,0 +1,143 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSSLCert(t *testing.T) {
+	tests := []struct {
+		name        string
+		out         string
+		expected    SSLCertT
+		expectedRaw map[string]string // A subset of the raw values
+	}{
+		{
+			name: "certificate with chain",
+			out: "Filename: /etc/haproxy/certs/www.example.com.pem\n" +
+				"Status: Used\n" +
+				"Serial: 0FD078DD48F1A2BD4D0F2BA96B6038FE\n" +
+				"notBefore: Sep  9 00:00:00 2019 GMT\n" +
+				"notAfter: Nov 10 12:00:00 2029 GMT\n" +
+				"Subject Alternative Name: DNS:www.example.com, DNS:example.com, IP Address:192.0.2.10\n" +
+				"Algorithm: RSA2048\n" +
+				"SHA1 FingerPrint: 7D6E8A2F4B1C9E0D3A5F6B7C8D9E0F1A2B3C4D5E\n" +
+				"Subject: /C=US/ST=California/L=Los Angeles/O=Example Inc./CN=www.example.com\n" +
+				"Issuer: /C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1\n" +
+				"Chain Subject: /C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1\n" +
+				"Chain Issuer: /C=US/O=DigiCert Inc/OU=www.digicert.com/CN=DigiCert Global Root CA\n" +
+				"OCSP Response Key: 303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a0414b76ba2eaa8aa848c79eab4da0f98b2c59576b9f4020a0fd078dd48f1a2bd4d0f\n",
+			expected: SSLCertT{
+				Filename:  "/etc/haproxy/certs/www.example.com.pem",
+				Status:    "Used",
+				Serial:    "0FD078DD48F1A2BD4D0F2BA96B6038FE",
+				NotBefore: time.Date(2019, time.September, 9, 0, 0, 0, 0, time.UTC),
+				NotAfter:  time.Date(2029, time.November, 10, 12, 0, 0, 0, time.UTC),
+				SANs:      []string{"www.example.com", "example.com", "192.0.2.10"},
+				Algorithm: "RSA2048",
+				SHA1:      "7D6E8A2F4B1C9E0D3A5F6B7C8D9E0F1A2B3C4D5E",
+				Subject:   "/C=US/ST=California/L=Los Angeles/O=Example Inc./CN=www.example.com",
+				Issuer:    "/C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1",
+				Chain: []SSLChainCertT{{
+					Subject: "/C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1",
+					Issuer:  "/C=US/O=DigiCert Inc/OU=www.digicert.com/CN=DigiCert Global Root CA",
+				}},
+				ChainComplete:   true,
+				OCSPResponseKey: "303b300906052b0e03021a050004148a83e0060faff709ca7e9b95522a2e81635fda0a0414b76ba2eaa8aa848c79eab4da0f98b2c59576b9f4020a0fd078dd48f1a2bd4d0f",
+			},
+			expectedRaw: map[string]string{
+				"Status":        "Used",
+				"notBefore":     "Sep  9 00:00:00 2019 GMT",
+				"Chain Subject": "/C=US/O=DigiCert Inc/CN=DigiCert TLS RSA SHA256 2020 CA1",
+			},
+		},
+		{
+			name: "certificate without intermediate",
+			out: "Filename: *www.example.com.pem\n" +
+				"Status: Uncommitted\n" +
+				"Serial: 02\n" +
+				"notBefore: Jan 15 08:30:00 2024 GMT\n" +
+				"notAfter: Jan 14 08:30:00 2025 GMT\n" +
+				"Subject Alternative Name: DNS:www.example.com\n" +
+				"Algorithm: EC256\n" +
+				"SHA1 FingerPrint: 0A1B2C3D4E5F60718293A4B5C6D7E8F901234567\n" +
+				"Subject: /CN=www.example.com\n" +
+				"Issuer: /CN=Example Intermediate CA\n",
+			expected: SSLCertT{
+				Filename:  "*www.example.com.pem",
+				Status:    "Uncommitted",
+				Serial:    "02",
+				NotBefore: time.Date(2024, time.January, 15, 8, 30, 0, 0, time.UTC),
+				NotAfter:  time.Date(2025, time.January, 14, 8, 30, 0, 0, time.UTC),
+				SANs:      []string{"www.example.com"},
+				Algorithm: "EC256",
+				SHA1:      "0A1B2C3D4E5F60718293A4B5C6D7E8F901234567",
+				Subject:   "/CN=www.example.com",
+				Issuer:    "/CN=Example Intermediate CA",
+				Chain:     []SSLChainCertT{},
+			},
+		},
+		{
+			name: "self signed",
+			out: "Filename: /etc/haproxy/certs/selfsigned.pem\n" +
+				"Status: Unused\n" +
+				"Serial: 5A1F\n" +
+				"notBefore: Mar  1 10:00:00 2023 GMT\n" +
+				"notAfter: Mar  1 10:00:00 2033 GMT\n" +
+				"Algorithm: RSA4096\n" +
+				"SHA1 FingerPrint: FFEEDDCCBBAA99887766554433221100FFEEDDCC\n" +
+				"Subject: /CN=localhost\n" +
+				"Issuer: /CN=localhost\n",
+			expected: SSLCertT{
+				Filename:      "/etc/haproxy/certs/selfsigned.pem",
+				Status:        "Unused",
+				Serial:        "5A1F",
+				NotBefore:     time.Date(2023, time.March, 1, 10, 0, 0, 0, time.UTC),
+				NotAfter:      time.Date(2033, time.March, 1, 10, 0, 0, 0, time.UTC),
+				SANs:          []string{},
+				Algorithm:     "RSA4096",
+				SHA1:          "FFEEDDCCBBAA99887766554433221100FFEEDDCC",
+				Subject:       "/CN=localhost",
+				Issuer:        "/CN=localhost",
+				Chain:         []SSLChainCertT{},
+				ChainComplete: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert := parseSSLCert(strings.Split(strings.TrimSpace(test.out), "\n"))
+			for key, value := range test.expectedRaw {
+				if cert.Raw[key] != value {
+					t.Errorf("raw %v is %q, expected %q", key, cert.Raw[key], value)
+				}
+			}
+			// The times are parsed in the GMT location, compare them separately
+			if !cert.NotBefore.Equal(test.expected.NotBefore) || !cert.NotAfter.Equal(test.expected.NotAfter) {
+				t.Errorf("got dates %v - %v, expected %v - %v", cert.NotBefore, cert.NotAfter, test.expected.NotBefore, test.expected.NotAfter)
+			}
+			cert.Raw = nil
+			cert.NotBefore = test.expected.NotBefore
+			cert.NotAfter = test.expected.NotAfter
+			if !reflect.DeepEqual(cert, test.expected) {
+				t.Errorf("got\n%+v\nexpected\n%+v", cert, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseSSLDate(t *testing.T) {
+	tests := map[string]time.Time{
+		"Sep 14 12:00:00 2021 GMT": time.Date(2021, time.September, 14, 12, 0, 0, 0, time.UTC),
+		"Sep  9 00:00:00 2019 GMT": time.Date(2019, time.September, 9, 0, 0, 0, 0, time.UTC),
+		"not a date":               {},
+	}
+	for in, expected := range tests {
+		if got := parseSSLDate(in); !got.Equal(expected) {
+			t.Errorf("parseSSLDate(%q) = %v, expected %v", in, got, expected)
+		}
+	}
+}
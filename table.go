@@ -0,0 +1,124 @@
+package haproxysocket
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableT is a stick table from "show table"
+type TableT struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // ip, ipv6, integer, string or binary
+	Size uint64 `json:"size"` // The max amount of entries
+	Used uint64 `json:"used"`
+}
+
+// TableEntryT is a single entry of a stick table
+type TableEntryT struct {
+	ID   string            `json:"id"`
+	Key  string            `json:"key"`
+	Use  uint64            `json:"use"`
+	Exp  time.Duration     `json:"exp"`
+	Data map[string]string `json:"data"` // The stored data, for example "gpc0" or "http_req_rate(10000)"
+}
+
+// ShowTables report the usage of all stick tables
+func (h *HaproxyInstace) ShowTables() ([]TableT, error) {
+	toReturn := []TableT{}
+	out, err := h.q("show table")
+	if err != nil {
+		return toReturn, err
+	}
+	if out == "" {
+		return toReturn, nil
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		table, ok := parseTableHeader(line)
+		if !ok {
+			return []TableT{}, errors.New(out)
+		}
+		toReturn = append(toReturn, table)
+	}
+	return toReturn, nil
+}
+
+// ShowTable dump the contents of a stick table
+func (h *HaproxyInstace) ShowTable(name string) (TableT, []TableEntryT, error) {
+	entries := []TableEntryT{}
+	if name == "" {
+		return TableT{}, entries, errors.New("name can't be an empty string")
+	}
+	out, err := h.q("show table " + name)
+	if err != nil {
+		return TableT{}, entries, err
+	}
+
+	// The output looks like:
+	// # table: http, type: ip, size:204800, used:1
+	// 0x55d8c1e2e8f0: key=127.0.0.1 use=0 exp=28s gpc0=0 http_req_rate(10000)=1
+	lines := strings.Split(out, "\n")
+	table, ok := parseTableHeader(lines[0])
+	if !ok {
+		return table, entries, errors.New(out)
+	}
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		parts := strings.Fields(line)
+		if !strings.HasPrefix(parts[0], "0x") {
+			return table, []TableEntryT{}, errors.New(out)
+		}
+		entry := TableEntryT{
+			ID:   strings.TrimSuffix(parts[0], ":"),
+			Data: map[string]string{},
+		}
+		for _, part := range parts[1:] {
+			keyVal := strings.SplitN(part, "=", 2)
+			if len(keyVal) != 2 {
+				continue
+			}
+			switch keyVal[0] {
+			case "key":
+				entry.Key = keyVal[1]
+			case "use":
+				entry.Use, _ = strconv.ParseUint(keyVal[1], 10, 64)
+			case "exp":
+				entry.Exp = parseDuration(keyVal[1])
+			default:
+				entry.Data[keyVal[0]] = keyVal[1]
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return table, entries, nil
+}
+
+// parseTableHeader parses a line like "# table: http, type: ip, size:204800, used:1"
+func parseTableHeader(line string) (TableT, bool) {
+	toReturn := TableT{}
+	if !strings.HasPrefix(line, "# table:") {
+		return toReturn, false
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(line, "# "), ",") {
+		keyVal := strings.SplitN(part, ":", 2)
+		if len(keyVal) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(keyVal[1])
+		switch strings.TrimSpace(keyVal[0]) {
+		case "table":
+			toReturn.Name = value
+		case "type":
+			toReturn.Type = value
+		case "size":
+			toReturn.Size, _ = strconv.ParseUint(value, 10, 64)
+		case "used":
+			toReturn.Used, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return toReturn, true
+}
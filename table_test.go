@@ -0,0 +1,110 @@
+package haproxysocket
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cannedInstance returns an instance that answers queries with canned output instead of connecting to haproxy
+func cannedInstance(t *testing.T, responses map[string]string) *HaproxyInstace {
+	h := New("unix", "/nonexistent.sock")
+	h.Interceptors = []InterceptorT{func(c *CommandT, next func() (string, error)) (string, error) {
+		out, ok := responses[c.Query]
+		if !ok {
+			// Errorf instead of Fatalf as the cluster tests run the queries from other goroutines
+			t.Errorf("unexpected query %q", c.Query)
+			return "", errors.New("unexpected query")
+		}
+		// The same as q, the output is trimmed
+		return strings.TrimSpace(out), nil
+	}}
+	return h
+}
+
+func TestShowTables(t *testing.T) {
+	h := cannedInstance(t, map[string]string{
+		"show table": "# table: be_app, type: ip, size:1048576, used:2\n" +
+			"# table: fe_rate, type: string, size:102400, used:0\n",
+	})
+	tables, err := h.ShowTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TableT{
+		{Name: "be_app", Type: "ip", Size: 1048576, Used: 2},
+		{Name: "fe_rate", Type: "string", Size: 102400, Used: 0},
+	}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("got %+v, expected %+v", tables, expected)
+	}
+}
+
+func TestShowTable(t *testing.T) {
+	tests := []struct {
+		name            string
+		out             string
+		expectedTable   TableT
+		expectedEntries []TableEntryT
+		expectErr       bool
+	}{
+		{
+			name: "entries",
+			out: "# table: be_app, type: ip, size:1048576, used:2\n" +
+				"0x55d4c7e4a2f0: key=127.0.0.1 use=0 exp=29s gpc0=0 conn_rate(10000)=1 http_req_rate(10000)=3\n" +
+				"0x55d4c7e4a3c0: key=10.0.0.5 use=1 exp=1m25s shard=0 gpc0=2 conn_rate(10000)=0 http_req_rate(10000)=0\n",
+			expectedTable: TableT{Name: "be_app", Type: "ip", Size: 1048576, Used: 2},
+			expectedEntries: []TableEntryT{
+				{
+					ID:   "0x55d4c7e4a2f0",
+					Key:  "127.0.0.1",
+					Use:  0,
+					Exp:  29 * time.Second,
+					Data: map[string]string{"gpc0": "0", "conn_rate(10000)": "1", "http_req_rate(10000)": "3"},
+				},
+				{
+					ID:   "0x55d4c7e4a3c0",
+					Key:  "10.0.0.5",
+					Use:  1,
+					Exp:  85 * time.Second,
+					Data: map[string]string{"shard": "0", "gpc0": "2", "conn_rate(10000)": "0", "http_req_rate(10000)": "0"},
+				},
+			},
+		},
+		{
+			name:            "empty table",
+			out:             "# table: fe_rate, type: string, size:102400, used:0\n",
+			expectedTable:   TableT{Name: "fe_rate", Type: "string", Size: 102400, Used: 0},
+			expectedEntries: []TableEntryT{},
+		},
+		{
+			name:      "unknown table",
+			out:       "Unknown table. Valid options are: be_app, fe_rate\n",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := cannedInstance(t, map[string]string{"show table be_app": test.out})
+			table, entries, err := h.ShowTable("be_app")
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if table != test.expectedTable {
+				t.Errorf("got table %+v, expected %+v", table, test.expectedTable)
+			}
+			if !reflect.DeepEqual(entries, test.expectedEntries) {
+				t.Errorf("got entries %+v, expected %+v", entries, test.expectedEntries)
+			}
+		})
+	}
+}
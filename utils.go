@@ -5,17 +5,23 @@ import (
 	"errors"
 	"io"
 	"net"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// q executes a query
-func (h *HaproxyInstace) q(query string) (string, error) {
+// exec sends a query to haproxy
+func (h *HaproxyInstace) exec(query string) (string, error) {
 	c, err := net.Dial(h.Network, h.Address)
 	if err != nil {
-		panic(err)
+		// Return the error instead of panicking so a single unreachable node doesn't crash a cluster
+		return "", err
 	}
 	defer c.Close()
 
+	if h.prefix != "" {
+		query = h.prefix + " " + query
+	}
 	c.Write([]byte(query + "\n"))
 
 	var buf bytes.Buffer
@@ -73,3 +79,76 @@ func csvToArrMap(in string, alternateSplit ...string) ([]map[string]string, erro
 
 	return toReturn, nil
 }
+
+// statUint returns a numeric value from a "show stat" row, empty or invalid values return 0
+func statUint(row map[string]string, key string) uint64 {
+	i, err := strconv.ParseUint(row[key], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// inList checks if item is in list
+func inList(item string, list []string) bool {
+	for _, listItem := range list {
+		if listItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDuration parses a haproxy human readable duration like "59s", "23h59m" or "1d2h"
+// Values like "<NEVER>", "<PAST>", "?" and "" return 0
+func parseDuration(in string) time.Duration {
+	negative := strings.HasPrefix(in, "-")
+	in = strings.TrimPrefix(in, "-")
+
+	var toReturn time.Duration
+	num := ""
+	for i := 0; i < len(in); i++ {
+		c := in[i]
+		if c >= '0' && c <= '9' {
+			num += string(c)
+			continue
+		}
+		if num == "" {
+			return 0
+		}
+		n, _ := strconv.Atoi(num)
+		num = ""
+		unit := time.Second
+		switch c {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+			if i+1 < len(in) && in[i+1] == 's' {
+				unit = time.Millisecond
+				i++
+			}
+		case 'u':
+			unit = time.Microsecond
+			if i+1 < len(in) && in[i+1] == 's' {
+				i++
+			}
+		case 's':
+		default:
+			return 0
+		}
+		toReturn += time.Duration(n) * unit
+	}
+	if num != "" {
+		// A number without a unit is in milliseconds
+		n, _ := strconv.Atoi(num)
+		toReturn += time.Duration(n) * time.Millisecond
+	}
+
+	if negative {
+		return -toReturn
+	}
+	return toReturn
+}
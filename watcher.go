@@ -0,0 +1,266 @@
+package haproxysocket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The kinds of watch events
+const (
+	WatchServerState   = "server_state"   // A server changed between UP, DOWN, NOLB, MAINT and DRAIN
+	WatchServerWeight  = "server_weight"  // The user weight of a server changed
+	WatchCheckStatus   = "check_status"   // The health check status of a server changed, for example from L7OK to L4TOUT
+	WatchServerAdded   = "server_added"   // A server appeared
+	WatchServerRemoved = "server_removed" // A server disappeared
+	WatchFlap          = "flap"           // A server changed state and changed back between 2 polls
+	WatchFrontendState = "frontend_state" // A frontend changed between OPEN, STOP (disabled) and FULL
+	WatchBackendDown   = "backend_down"   // A backend has no usable servers left
+	WatchBackendUp     = "backend_up"     // A backend has usable servers again
+	WatchError         = "error"          // Polling failed
+)
+
+// WatchEventT is a change found by the WatcherT
+type WatchEventT struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Proxy   string    `json:"proxy"`
+	Server  string    `json:"server,omitempty"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to,omitempty"`
+	Flaps   uint64    `json:"flaps,omitempty"` // Only for flap events, the amount of times the server went down, 0 if unknown
+	Message string    `json:"message"`
+}
+
+// watchStateT is the state of a single "show stat" row
+type watchStateT struct {
+	kind    string // frontend, backend or server
+	status  string
+	weight  string
+	check   string
+	chkdown uint64
+	// lastchg is the amount of seconds since the last state change
+	lastchg    uint64
+	hasLastchg bool
+}
+
+// WatcherT polls the stats and server states and reports the changes, create one using Watcher
+type WatcherT struct {
+	h *HaproxyInstace
+	m sync.Mutex
+
+	Interval time.Duration // The time between polls when using Run, defaults to 2 seconds
+
+	previous     map[string]watchStateT
+	previousTime time.Time
+	previousPID  string
+	now          func() time.Time // Returns the time of a check, replaced in the tests
+}
+
+// Watcher creates a state change watcher
+func (h *HaproxyInstace) Watcher() *WatcherT {
+	return &WatcherT{
+		h:   h,
+		now: time.Now,
+	}
+}
+
+// Check polls once and returns the changes since the previous check
+// The first check only records the current state and doesn't return events
+func (w *WatcherT) Check() ([]WatchEventT, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	toReturn := []WatchEventT{}
+	now := w.now()
+
+	info, err := w.h.ShowInfo()
+	if err != nil {
+		return toReturn, err
+	}
+	stats, err := w.h.ShowStat()
+	if err != nil {
+		return toReturn, err
+	}
+	servers, err := w.h.ShowServersState()
+	if err != nil {
+		return toReturn, err
+	}
+	adminStates := map[string]SrvAdminState{}
+	weights := map[string]string{}
+	for _, s := range servers {
+		adminStates[s.Backend+"/"+s.Server] = s.AdminState
+		weights[s.Backend+"/"+s.Server] = strconv.Itoa(s.UWeight)
+	}
+
+	current := map[string]watchStateT{}
+	keys := []string{}
+	for _, row := range stats {
+		key := row["pxname"] + "/" + row["svname"]
+		state := watchStateT{
+			status:  row["status"],
+			check:   strings.TrimPrefix(row["check_status"], "* "),
+			chkdown: statUint(row, "chkdown"),
+			lastchg: statUint(row, "lastchg"),
+		}
+		state.hasLastchg = row["lastchg"] != ""
+		switch row["type"] {
+		case "0":
+			state.kind = "frontend"
+		case "1":
+			state.kind = "backend"
+		case "2":
+			state.kind = "server"
+			state.status = serverStatus(row["status"], adminStates[key])
+			state.weight = weights[key]
+			if state.weight == "" {
+				state.weight = row["weight"]
+			}
+		default:
+			continue
+		}
+		current[key] = state
+		keys = append(keys, key)
+	}
+
+	previous := w.previous
+	elapsed := now.Sub(w.previousTime)
+	// After a restart the counters and lastchg are reset so flaps can't be detected
+	restarted := info["Pid"] != w.previousPID
+	w.previous = current
+	w.previousTime = now
+	w.previousPID = info["Pid"]
+	if previous == nil {
+		return toReturn, nil
+	}
+
+	event := func(kind, key, from, to, message string) WatchEventT {
+		parts := strings.SplitN(key, "/", 2)
+		toAdd := WatchEventT{
+			Time:    now,
+			Kind:    kind,
+			Proxy:   parts[0],
+			From:    from,
+			To:      to,
+			Message: message,
+		}
+		if current[key].kind == "server" || previous[key].kind == "server" {
+			toAdd.Server = parts[1]
+		}
+		return toAdd
+	}
+
+	for _, key := range keys {
+		state := current[key]
+		before, ok := previous[key]
+		if !ok {
+			if state.kind == "server" {
+				toReturn = append(toReturn, event(WatchServerAdded, key, "", state.status, "server "+key+" was added"))
+			}
+			continue
+		}
+
+		switch state.kind {
+		case "frontend":
+			if state.status != before.status {
+				toReturn = append(toReturn, event(WatchFrontendState, key, before.status, state.status, "frontend "+key+" changed from "+before.status+" to "+state.status))
+			}
+		case "backend":
+			if state.status == before.status {
+				break
+			}
+			if state.status == "DOWN" {
+				toReturn = append(toReturn, event(WatchBackendDown, key, before.status, state.status, "backend "+strings.TrimSuffix(key, "/BACKEND")+" has no usable servers left"))
+			} else if before.status == "DOWN" {
+				toReturn = append(toReturn, event(WatchBackendUp, key, before.status, state.status, "backend "+strings.TrimSuffix(key, "/BACKEND")+" has usable servers again"))
+			}
+		case "server":
+			if state.status != before.status {
+				toReturn = append(toReturn, event(WatchServerState, key, before.status, state.status, "server "+key+" changed from "+before.status+" to "+state.status))
+			} else if !restarted && state.chkdown > before.chkdown {
+				flaps := state.chkdown - before.chkdown
+				toAdd := event(WatchFlap, key, before.status, state.status, fmt.Sprintf("server %v went down %v times since the previous check", key, flaps))
+				toAdd.Flaps = flaps
+				toReturn = append(toReturn, toAdd)
+			} else if !restarted && state.hasLastchg && before.hasLastchg && state.lastchg+1 < before.lastchg+uint64(elapsed.Seconds()) {
+				// lastchg is in seconds so allow 1 second of rounding
+				toReturn = append(toReturn, event(WatchFlap, key, before.status, state.status, "server "+key+" changed state and back since the previous check"))
+			}
+			if state.weight != before.weight {
+				toReturn = append(toReturn, event(WatchServerWeight, key, before.weight, state.weight, "weight of server "+key+" changed from "+before.weight+" to "+state.weight))
+			}
+			if state.check != before.check && state.check != "" && before.check != "" {
+				toReturn = append(toReturn, event(WatchCheckStatus, key, before.check, state.check, "check status of server "+key+" changed from "+before.check+" to "+state.check))
+			}
+		}
+	}
+
+	removed := []string{}
+	for key, state := range previous {
+		if _, ok := current[key]; !ok && state.kind == "server" {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		toReturn = append(toReturn, event(WatchServerRemoved, key, previous[key].status, "", "server "+key+" was removed"))
+	}
+
+	return toReturn, nil
+}
+
+// serverStatus returns UP, DOWN, NOLB, MAINT or DRAIN
+func serverStatus(status string, admin SrvAdminState) string {
+	switch {
+	case admin.Maint() || strings.HasPrefix(status, "MAINT"):
+		return "MAINT"
+	case admin.Drain() || strings.HasPrefix(status, "DRAIN"):
+		return "DRAIN"
+	case status == "no check":
+		return "UP"
+	}
+	// Transitional states look like "UP 1/3" or "DOWN 1/2"
+	fields := strings.Fields(status)
+	if len(fields) == 0 {
+		return status
+	}
+	return fields[0]
+}
+
+// Run checks every Interval and sends the events on the returned channel until ctx is done
+// Failed polls are sent as WatchError events, the channel is closed when ctx is done
+func (w *WatcherT) Run(ctx context.Context) <-chan WatchEventT {
+	interval := w.Interval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	events := make(chan WatchEventT)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			found, err := w.Check()
+			if err != nil {
+				found = []WatchEventT{{Time: time.Now(), Kind: WatchError, Message: err.Error()}}
+			}
+			for _, e := range found {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,186 @@
+package haproxysocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const watcherStatHeader = "# pxname,svname,status,weight,chkdown,lastchg,check_status,type,"
+
+// watcherServersState returns "show servers state" output for be_app/app1 and be_app/app2
+func watcherServersState(app1Admin, app1Weight, app2Admin string) string {
+	return "1\n" + serversStateHeader + "\n" +
+		"3 be_app 1 app1 10.0.0.1 2 " + app1Admin + " " + app1Weight + " 1 100 6 3 4 6 0 0 0 - 80 - 0 0 - - 0\n" +
+		"3 be_app 2 app2 10.0.0.2 2 " + app2Admin + " 1 1 100 6 3 4 6 0 0 0 - 80 - 0 0 - - 0\n"
+}
+
+// watchEventKeyT are the fields of an event that are compared in the tests
+type watchEventKeyT struct {
+	Kind   string
+	Proxy  string
+	Server string
+	From   string
+	To     string
+	Flaps  uint64
+}
+
+func TestWatcher(t *testing.T) {
+	firstStat := watcherStatHeader + "\n" +
+		"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+		"be_app,app1,UP,1,0,100,L7OK,2,\n" +
+		"be_app,app2,UP,1,0,100,L7OK,2,\n" +
+		"be_app,BACKEND,UP,2,0,100,,1,\n"
+	firstState := watcherServersState("0", "1", "0")
+
+	tests := []struct {
+		name        string
+		secondPid   string
+		secondStat  string
+		secondState string
+		expected    []watchEventKeyT
+	}{
+		{
+			name: "nothing changed",
+			// lastchg went up by the 10 seconds between the checks
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+				"be_app,app1,UP,1,0,110,L7OK,2,\n" +
+				"be_app,app2,UP,1,0,110,L7OK,2,\n" +
+				"be_app,BACKEND,UP,2,0,110,,1,\n",
+			secondState: firstState,
+			expected:    []watchEventKeyT{},
+		},
+		{
+			name: "backend without usable servers",
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+				"be_app,app1,DOWN,1,1,0,* L4TOUT,2,\n" +
+				"be_app,app2,DOWN 1/2,1,1,0,L4CON,2,\n" +
+				"be_app,BACKEND,DOWN,0,1,0,,1,\n",
+			secondState: firstState,
+			expected: []watchEventKeyT{
+				{Kind: WatchServerState, Proxy: "be_app", Server: "app1", From: "UP", To: "DOWN"},
+				{Kind: WatchCheckStatus, Proxy: "be_app", Server: "app1", From: "L7OK", To: "L4TOUT"},
+				{Kind: WatchServerState, Proxy: "be_app", Server: "app2", From: "UP", To: "DOWN"},
+				{Kind: WatchCheckStatus, Proxy: "be_app", Server: "app2", From: "L7OK", To: "L4CON"},
+				{Kind: WatchBackendDown, Proxy: "be_app", From: "UP", To: "DOWN"},
+			},
+		},
+		{
+			name: "flap counted by chkdown",
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+				"be_app,app1,UP,1,2,1,L7OK,2,\n" +
+				"be_app,app2,UP,1,0,110,L7OK,2,\n" +
+				"be_app,BACKEND,UP,2,0,110,,1,\n",
+			secondState: firstState,
+			expected: []watchEventKeyT{
+				{Kind: WatchFlap, Proxy: "be_app", Server: "app1", From: "UP", To: "UP", Flaps: 2},
+			},
+		},
+		{
+			name: "flap detected by lastchg",
+			// app1 went to NOLB and back, that doesn't change chkdown
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+				"be_app,app1,UP,1,0,3,L7OK,2,\n" +
+				"be_app,app2,UP,1,0,110,L7OK,2,\n" +
+				"be_app,BACKEND,UP,2,0,110,,1,\n",
+			secondState: firstState,
+			expected: []watchEventKeyT{
+				{Kind: WatchFlap, Proxy: "be_app", Server: "app1", From: "UP", To: "UP"},
+			},
+		},
+		{
+			name:      "no flaps after a restart",
+			secondPid: "2",
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+				"be_app,app1,UP,1,3,2,L7OK,2,\n" +
+				"be_app,app2,UP,1,0,2,L7OK,2,\n" +
+				"be_app,BACKEND,UP,2,0,2,,1,\n",
+			secondState: firstState,
+			expected:    []watchEventKeyT{},
+		},
+		{
+			name: "drain and weight from the admin state",
+			// A drained server is reported as DRAIN even if "show stat" only shows its health
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,STOP,,,,,0,\n" +
+				"be_app,app1,UP,0,0,110,L7OK,2,\n" +
+				"be_app,app2,UP,1,0,110,L7OK,2,\n" +
+				"be_app,BACKEND,UP,1,0,110,,1,\n",
+			secondState: watcherServersState("8", "0", "0"),
+			expected: []watchEventKeyT{
+				{Kind: WatchFrontendState, Proxy: "fe_http", From: "OPEN", To: "STOP"},
+				{Kind: WatchServerState, Proxy: "be_app", Server: "app1", From: "UP", To: "DRAIN"},
+				{Kind: WatchServerWeight, Proxy: "be_app", Server: "app1", From: "1", To: "0"},
+			},
+		},
+		{
+			name: "server added and removed",
+			secondStat: watcherStatHeader + "\n" +
+				"fe_http,FRONTEND,OPEN,,,,,0,\n" +
+				"be_app,app1,UP,1,0,110,L7OK,2,\n" +
+				"be_app,app3,UP,1,0,5,L7OK,2,\n" +
+				"be_app,BACKEND,UP,2,0,110,,1,\n",
+			secondState: firstState,
+			expected: []watchEventKeyT{
+				{Kind: WatchServerAdded, Proxy: "be_app", Server: "app3", To: "UP"},
+				{Kind: WatchServerRemoved, Proxy: "be_app", Server: "app2", From: "UP"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secondPid := test.secondPid
+			if secondPid == "" {
+				secondPid = "1"
+			}
+			w := sequenceInstance(t, map[string][]string{
+				"show info":          {"Pid: 1", "Pid: " + secondPid},
+				"show stat":          {firstStat, test.secondStat},
+				"show servers state": {firstState, test.secondState},
+			}).Watcher()
+			start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+			times := []time.Time{start, start.Add(10 * time.Second)}
+			w.now = func() time.Time {
+				now := times[0]
+				times = times[1:]
+				return now
+			}
+
+			events, err := w.Check()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 0 {
+				t.Fatalf("expected no events on the first check, got %v", events)
+			}
+
+			events, err = w.Check()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []watchEventKeyT{}
+			for _, event := range events {
+				if !event.Time.Equal(start.Add(10 * time.Second)) {
+					t.Errorf("event %v has time %v", event.Kind, event.Time)
+				}
+				got = append(got, watchEventKeyT{
+					Kind:   event.Kind,
+					Proxy:  event.Proxy,
+					Server: event.Server,
+					From:   event.From,
+					To:     event.To,
+					Flaps:  event.Flaps,
+				})
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("got\n%+v\nexpected\n%+v", got, test.expected)
+			}
+		})
+	}
+}